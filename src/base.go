@@ -1,44 +1,266 @@
 package main
 
 import (
+	"math"
+
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
 
+	"github.com/memmaker/ECon/console"
 	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/input"
 )
 
 type InputState struct {
-	MousePos     geometry.Point
-	LastMousePos geometry.Point
+	MousePos          geometry.Point
+	HalfWidthMousePos geometry.Point
+	LastMousePos      geometry.Point
+	Actions           *input.ActionMap // bindings of the menu actions, nil for the defaults
+	events            input.EventQueue
+	// Gamepads
+	Cursor          *input.VirtualCursor // moved by the right stick
+	gamepads        []ebiten.GamepadID   // with the standard layout
+	gamepadButtons  []string             // just pressed in the frame
+	gamepadDir      geometry.Point
+	dirRepeat       input.DirectionRepeater
+	lastMousePixels geometry.Point
+	cursorLeft      bool // virtual cursor clicks
+	cursorRight     bool
+}
+
+// The key repeat timing of the KeyRepeat events, in ticks.
+const (
+	keyRepeatDelay    = 30
+	keyRepeatInterval = 3
+)
+
+var gamepadButtonNames = [...]string{
+	ebiten.StandardGamepadButtonRightBottom:      input.GamepadRightBottom,
+	ebiten.StandardGamepadButtonRightRight:       input.GamepadRightRight,
+	ebiten.StandardGamepadButtonRightLeft:        input.GamepadRightLeft,
+	ebiten.StandardGamepadButtonRightTop:         input.GamepadRightTop,
+	ebiten.StandardGamepadButtonFrontTopLeft:     input.GamepadFrontTopLeft,
+	ebiten.StandardGamepadButtonFrontTopRight:    input.GamepadFrontTopRight,
+	ebiten.StandardGamepadButtonFrontBottomLeft:  input.GamepadFrontBottomLeft,
+	ebiten.StandardGamepadButtonFrontBottomRight: input.GamepadFrontBottomRight,
+	ebiten.StandardGamepadButtonCenterLeft:       input.GamepadCenterLeft,
+	ebiten.StandardGamepadButtonCenterRight:      input.GamepadCenterRight,
+	ebiten.StandardGamepadButtonLeftStick:        input.GamepadLeftStick,
+	ebiten.StandardGamepadButtonRightStick:       input.GamepadRightStick,
+	ebiten.StandardGamepadButtonLeftTop:          input.GamepadLeftTop,
+	ebiten.StandardGamepadButtonLeftBottom:       input.GamepadLeftBottom,
+	ebiten.StandardGamepadButtonLeftLeft:         input.GamepadLeftLeft,
+	ebiten.StandardGamepadButtonLeftRight:        input.GamepadLeftRight,
+	ebiten.StandardGamepadButtonCenterCenter:     input.GamepadCenterCenter,
+}
+
+// The gamepad buttons clicking with the virtual cursor.
+const (
+	cursorLeftButton  = ebiten.StandardGamepadButtonRightBottom
+	cursorRightButton = ebiten.StandardGamepadButtonRightLeft
+)
+
+// pollGamepads reads the gamepads with the standard layout, after the mouse
+// positions are updated. While the virtual cursor is active, it replaces the
+// mouse positions. A move of the mouse deactivates it.
+func (i *InputState) pollGamepads(gridSize, mousePixels geometry.Point) {
+	for _, id := range inpututil.AppendJustConnectedGamepadIDs(nil) {
+		if ebiten.IsStandardGamepadLayoutAvailable(id) {
+			i.gamepads = append(i.gamepads, id)
+		}
+	}
+	i.gamepadButtons = i.gamepadButtons[:0]
+	i.cursorLeft, i.cursorRight = false, false
+	var dir, cursorDir geometry.Point
+	for _, id := range i.gamepads {
+		if inpututil.IsGamepadJustDisconnected(id) {
+			continue
+		}
+		for _, b := range inpututil.AppendJustPressedStandardGamepadButtons(id, nil) {
+			i.gamepadButtons = append(i.gamepadButtons, gamepadButtonNames[b])
+			i.cursorLeft = i.cursorLeft || b == cursorLeftButton
+			i.cursorRight = i.cursorRight || b == cursorRightButton
+		}
+		dir = dir.Add(input.DPadDirection(
+			ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonLeftTop),
+			ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonLeftBottom),
+			ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonLeftLeft),
+			ebiten.IsStandardGamepadButtonPressed(id, ebiten.StandardGamepadButtonLeftRight),
+		))
+		dir = dir.Add(input.StickDirection(
+			ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickHorizontal),
+			ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisLeftStickVertical),
+			input.StickDeadzone,
+		))
+		cursorDir = cursorDir.Add(input.StickDirection(
+			ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisRightStickHorizontal),
+			ebiten.StandardGamepadAxisValue(id, ebiten.StandardGamepadAxisRightStickVertical),
+			input.StickDeadzone,
+		))
+	}
+	i.gamepadDir = i.dirRepeat.Update(clampDirection(dir))
+
+	if mousePixels != i.lastMousePixels {
+		i.Cursor.Active = false
+	}
+	i.lastMousePixels = mousePixels
+	if !i.Cursor.Active {
+		i.Cursor.Pos = i.MousePos
+	}
+	i.Cursor.Update(clampDirection(cursorDir), geometry.NewRect(0, 0, gridSize.X, gridSize.Y))
+	if i.Cursor.Active {
+		i.MousePos = i.Cursor.Pos
+		i.HalfWidthMousePos = console.SquareToHalfWidth(i.Cursor.Pos)
+	} else {
+		i.cursorLeft, i.cursorRight = false, false
+	}
+}
+
+// clampDirection reduces the sum of the directions of several gamepads to a
+// direction.
+func clampDirection(dir geometry.Point) geometry.Point {
+	if dir.X != 0 {
+		dir.X /= abs(dir.X)
+	}
+	if dir.Y != 0 {
+		dir.Y /= abs(dir.Y)
+	}
+	return dir
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+var mouseButtons = [...]ebiten.MouseButton{
+	input.ButtonLeft:   ebiten.MouseButtonLeft,
+	input.ButtonRight:  ebiten.MouseButtonRight,
+	input.ButtonMiddle: ebiten.MouseButtonMiddle,
+}
+
+// pollEvents queues the events of the frame, after the mouse positions are
+// updated. ebiten only reports the state at each tick, so the events of a
+// tick are queued in a fixed order: the mouse move, the buttons, the wheel,
+// the keys, the typed characters and the gamepads.
+func (i *InputState) pollEvents() {
+	q := &i.events
+	q.Clear()
+	var mods input.Modifiers
+	if i.IsShiftDown() {
+		mods |= input.ModShift
+	}
+	if i.IsControlDown() {
+		mods |= input.ModControl
+	}
+	if i.IsAltDown() {
+		mods |= input.ModAlt
+	}
+	q.SetModifiers(mods)
+	if !i.Cursor.Active {
+		// before the mouse buttons, which would be ignored if still held
+		i.Cursor.ReleaseAll(q)
+	}
+	q.MouseMove(i.MousePos, i.HalfWidthMousePos)
+	for b, button := range mouseButtons {
+		if inpututil.IsMouseButtonJustPressed(button) {
+			q.MouseDown(input.MouseButton(b))
+		}
+		if inpututil.IsMouseButtonJustReleased(button) {
+			q.MouseUp(input.MouseButton(b))
+		}
+	}
+	q.Wheel(i.GetWheel())
+	for _, key := range inpututil.AppendJustPressedKeys(nil) {
+		q.KeyDown(key.String())
+	}
+	for _, key := range inpututil.AppendPressedKeys(nil) {
+		d := inpututil.KeyPressDuration(key)
+		if d > keyRepeatDelay && (d-keyRepeatDelay)%keyRepeatInterval == 0 {
+			q.KeyRepeat(key.String())
+		}
+	}
+	for _, key := range inpututil.AppendJustReleasedKeys(nil) {
+		q.KeyUp(key.String())
+	}
+	for _, r := range ebiten.AppendInputChars(nil) {
+		q.Char(r)
+	}
+	i.queueGamepadEvents()
+}
+
+// queueGamepadEvents queues the connections, the buttons and the clicks of
+// the virtual cursor, and forgets the disconnected gamepads.
+func (i *InputState) queueGamepadEvents() {
+	q := &i.events
+	for _, id := range inpututil.AppendJustConnectedGamepadIDs(nil) {
+		q.GamepadConnected(int(id))
+	}
+	gamepads := i.gamepads[:0]
+	for _, id := range i.gamepads {
+		if inpututil.IsGamepadJustDisconnected(id) {
+			q.GamepadDisconnected(int(id))
+			i.Cursor.ReleaseAll(q)
+			continue
+		}
+		gamepads = append(gamepads, id)
+		for _, b := range inpututil.AppendJustPressedStandardGamepadButtons(id, nil) {
+			q.GamepadDown(int(id), gamepadButtonNames[b])
+		}
+		for _, b := range inpututil.AppendJustReleasedStandardGamepadButtons(id, nil) {
+			q.GamepadUp(int(id), gamepadButtonNames[b])
+		}
+		if !i.Cursor.Active {
+			continue
+		}
+		if inpututil.IsStandardGamepadButtonJustPressed(id, cursorLeftButton) {
+			i.Cursor.Press(q, input.ButtonLeft)
+		}
+		if inpututil.IsStandardGamepadButtonJustReleased(id, cursorLeftButton) {
+			i.Cursor.Release(q, input.ButtonLeft)
+		}
+		if inpututil.IsStandardGamepadButtonJustPressed(id, cursorRightButton) {
+			i.Cursor.Press(q, input.ButtonRight)
+		}
+		if inpututil.IsStandardGamepadButtonJustReleased(id, cursorRightButton) {
+			i.Cursor.Release(q, input.ButtonRight)
+		}
+	}
+	i.gamepads = gamepads
 }
 
 func (i InputState) IsMenuClose() bool {
-	return inpututil.IsKeyJustPressed(ebiten.KeyEscape)
+	return input.IsMenuTriggered(i, i.Actions, input.MenuClose)
 }
 
 func (i InputState) IsMenuConfirm() bool {
-	return inpututil.IsKeyJustPressed(ebiten.KeyEnter) || i.IsMouseLeft()
+	return input.IsMenuTriggered(i, i.Actions, input.MenuConfirm)
 }
 
 func (i InputState) IsMenuDown() bool {
-	return inpututil.IsKeyJustPressed(ebiten.KeyDown) || inpututil.IsKeyJustPressed(ebiten.KeyS)
+	return input.IsMenuTriggered(i, i.Actions, input.MenuDown) || i.gamepadDir.Y > 0
 }
 
 func (i InputState) IsMenuUp() bool {
-	return inpututil.IsKeyJustPressed(ebiten.KeyUp) || inpututil.IsKeyJustPressed(ebiten.KeyW)
+	return input.IsMenuTriggered(i, i.Actions, input.MenuUp) || i.gamepadDir.Y < 0
 }
 
 func (i InputState) GetMousePos() geometry.Point {
 	return i.MousePos
 }
+func (i InputState) GetHalfWidthMousePos() geometry.Point {
+	return i.HalfWidthMousePos
+}
 func (i InputState) HasMouseMoved() bool {
 	return i.MousePos != i.LastMousePos
 }
 func (i InputState) IsMouseLeft() bool {
-	return inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft)
+	return inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) || i.cursorLeft
 }
 func (i InputState) IsMouseRight() bool {
-	return inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight)
+	return inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonRight) || i.cursorRight
 }
 
 func (i InputState) GetJustPressedKeys() []string {
@@ -51,6 +273,45 @@ func (i InputState) GetJustPressedKeys() []string {
 	return keys
 }
 
+func (i InputState) GetInputChars() []rune {
+	return ebiten.AppendInputChars(nil)
+}
+
+func (i InputState) IsShiftDown() bool {
+	return ebiten.IsKeyPressed(ebiten.KeyShift)
+}
+
+func (i InputState) IsControlDown() bool {
+	return ebiten.IsKeyPressed(ebiten.KeyControl)
+}
+
+func (i InputState) IsAltDown() bool {
+	return ebiten.IsKeyPressed(ebiten.KeyAlt)
+}
+
+func (i InputState) GetWheel() int {
+	_, dy := ebiten.Wheel()
+	return int(math.Round(dy))
+}
+
+func (i InputState) GetEvents() []input.Event {
+	return i.events.Events()
+}
+
+func (i InputState) GetJustPressedGamepadButtons() []string {
+	buttons := make([]string, len(i.gamepadButtons))
+	copy(buttons, i.gamepadButtons)
+	return buttons
+}
+
+func (i InputState) GetGamepadDirection() geometry.Point {
+	return i.gamepadDir
+}
+
 func NewInput() *InputState {
-	return &InputState{}
+	return &InputState{
+		Actions:   input.MenuActions(),
+		Cursor:    input.NewVirtualCursor(),
+		dirRepeat: input.DirectionRepeater{Delay: input.DirectionRepeatDelay, Interval: input.DirectionRepeatInterval},
+	}
 }
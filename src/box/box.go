@@ -0,0 +1,213 @@
+// Package box draws frames, borders and separators with box-drawing runes
+// into a text.Canvas, like a console.CellInterface or a geometry.Grid.
+//
+// Lines join the lines already drawn: a separator ending on the side of a
+// frame turns it into a tee, and two crossing lines make a cross. Joins
+// between different line styles use the style of the line drawn last.
+package box
+
+import (
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/text"
+)
+
+// LineStyle is a set of box-drawing runes.
+type LineStyle int
+
+const (
+	Single LineStyle = iota
+	Double
+	Heavy
+	Rounded // Single with rounded corners
+	ASCII   // '-', '|' and '+', for fonts without box-drawing runes
+)
+
+// directions a line leaves a cell in, combined in masks indexing the glyphs
+const (
+	up = 1 << iota
+	down
+	left
+	right
+)
+
+// glyphs gives, for each line style, the rune of each combination of
+// directions. A single direction uses the straight line.
+var glyphs = [...][16]rune{
+	Single:  {' ', '│', '│', '│', '─', '┘', '┐', '┤', '─', '└', '┌', '├', '─', '┴', '┬', '┼'},
+	Double:  {' ', '║', '║', '║', '═', '╝', '╗', '╣', '═', '╚', '╔', '╠', '═', '╩', '╦', '╬'},
+	Heavy:   {' ', '┃', '┃', '┃', '━', '┛', '┓', '┫', '━', '┗', '┏', '┣', '━', '┻', '┳', '╋'},
+	Rounded: {' ', '│', '│', '│', '─', '╯', '╮', '┤', '─', '╰', '╭', '├', '─', '┴', '┬', '┼'},
+	ASCII:   {' ', '|', '|', '|', '-', '+', '+', '+', '-', '+', '+', '+', '-', '+', '+', '+'},
+}
+
+// masks gives the directions of the runes of all the styles. The straight
+// lines and ASCII '+' are registered with their full directions.
+var masks = func() map[rune]int {
+	m := make(map[rune]int)
+	for _, style := range glyphs {
+		for mask := len(style) - 1; mask > 0; mask-- {
+			r := style[mask]
+			if _, ok := m[r]; !ok && r != '+' {
+				m[r] = mask
+			}
+		}
+	}
+	m['+'] = up | down | left | right
+	return m
+}()
+
+// Runes returns the runes used by the style, for font coverage checks.
+func (ls LineStyle) Runes() string {
+	seen := make(map[rune]bool)
+	var runes []rune
+	for _, r := range glyphs[ls][1:] {
+		if !seen[r] {
+			seen[r] = true
+			runes = append(runes, r)
+		}
+	}
+	return string(runes)
+}
+
+// Horizontal returns the rune of a horizontal line of the style.
+func (ls LineStyle) Horizontal() rune {
+	return glyphs[ls][left|right]
+}
+
+// Vertical returns the rune of a vertical line of the style.
+func (ls LineStyle) Vertical() rune {
+	return glyphs[ls][up|down]
+}
+
+// Fallback returns the style itself if the font has all its runes, and ASCII
+// otherwise. hasGlyph is for example offscreen.Painter.HasGlyph, or a test of
+// ebitenrenderer.Renderer.FontFor against nil.
+func (ls LineStyle) Fallback(hasGlyph func(r rune) bool) LineStyle {
+	for _, r := range ls.Runes() {
+		if !hasGlyph(r) {
+			return ASCII
+		}
+	}
+	return ls
+}
+
+// join draws at p the rune of the directions of the line being drawn, added to
+// the directions of the line rune already there.
+func join(c text.Canvas, p geometry.Point, mask int, ls LineStyle, st text.Style) {
+	below := c.At(p)
+	mask |= masks[below.Char]
+	cell := common.Cell{Char: glyphs[ls][mask], Foreground: st.Fg, Background: st.Bg}
+	if cell.Foreground == nil {
+		cell.Foreground = below.Foreground
+	}
+	if cell.Background == nil {
+		cell.Background = below.Background
+	}
+	c.Set(p, cell)
+}
+
+// HLine draws a horizontal line of the given length starting at p, joining
+// the lines it meets, including at its ends.
+func HLine(c text.Canvas, p geometry.Point, length int, ls LineStyle, st text.Style) {
+	for i := 0; i < length; i++ {
+		mask := left | right
+		if i == 0 {
+			mask &^= left
+		}
+		if i == length-1 {
+			mask &^= right
+		}
+		join(c, p.Shift(i, 0), mask, ls, st)
+	}
+}
+
+// VLine draws a vertical line of the given length starting at p, joining the
+// lines it meets, including at its ends.
+func VLine(c text.Canvas, p geometry.Point, length int, ls LineStyle, st text.Style) {
+	for i := 0; i < length; i++ {
+		mask := up | down
+		if i == 0 {
+			mask &^= up
+		}
+		if i == length-1 {
+			mask &^= down
+		}
+		join(c, p.Shift(0, i), mask, ls, st)
+	}
+}
+
+// Frame draws the border of a range. The inside of the range is not changed.
+func Frame(c text.Canvas, rg geometry.Rect, ls LineStyle, st text.Style) {
+	size := rg.Size()
+	if size.X < 2 || size.Y < 2 {
+		return
+	}
+	// the corners are joined with their two directions at once, since the
+	// rune of a line end can't be told from the one of a whole line
+	max := rg.Max.Shift(-1, -1)
+	join(c, rg.Min, down|right, ls, st)
+	join(c, geometry.Point{X: max.X, Y: rg.Min.Y}, down|left, ls, st)
+	join(c, geometry.Point{X: rg.Min.X, Y: max.Y}, up|right, ls, st)
+	join(c, max, up|left, ls, st)
+	for x := rg.Min.X + 1; x < max.X; x++ {
+		join(c, geometry.Point{X: x, Y: rg.Min.Y}, left|right, ls, st)
+		join(c, geometry.Point{X: x, Y: max.Y}, left|right, ls, st)
+	}
+	for y := rg.Min.Y + 1; y < max.Y; y++ {
+		join(c, geometry.Point{X: rg.Min.X, Y: y}, up|down, ls, st)
+		join(c, geometry.Point{X: max.X, Y: y}, up|down, ls, st)
+	}
+}
+
+// TitledFrame draws the border of a range with a title on its top side,
+// surrounded by spaces. The title may contain text markup; it is truncated
+// with an ellipsis if it is too long.
+func TitledFrame(c text.Canvas, rg geometry.Rect, ls LineStyle, st text.Style, title string, align text.Align) {
+	Frame(c, rg, ls, st)
+	width := rg.Size().X - 4 // corners and spaces
+	if width < 1 || title == "" {
+		return
+	}
+	line := text.Layout(title, width, text.Options{Style: st})[0]
+	x := 1
+	switch align {
+	case text.AlignCenter:
+		x += (width - len(line)) / 2
+	case text.AlignRight:
+		x += width - len(line)
+	}
+	y := rg.Min.Y
+	x += rg.Min.X
+	text.Print(c, geometry.NewRect(x, y, x+1, y+1), " ", text.Options{Style: st})
+	text.Print(c, geometry.NewRect(x+1, y, x+1+len(line), y+1), title, text.Options{Style: st})
+	text.Print(c, geometry.NewRect(x+1+len(line), y, x+2+len(line), y+1), " ", text.Options{Style: st})
+}
+
+// Panel fills a range with a background and draws its border.
+func Panel(c text.Canvas, rg geometry.Rect, ls LineStyle, st text.Style) {
+	fill := common.Cell{Char: ' ', Foreground: st.Fg, Background: st.Bg}
+	rg.Iter(func(p geometry.Point) {
+		cell := fill
+		if cell.Foreground == nil {
+			cell.Foreground = c.At(p).Foreground
+		}
+		if cell.Background == nil {
+			cell.Background = c.At(p).Background
+		}
+		c.Set(p, cell)
+	})
+	Frame(c, rg, ls, st)
+}
+
+// HSeparator draws a horizontal line across a framed range at row y, relative
+// to the range, joining the sides of the frame.
+func HSeparator(c text.Canvas, rg geometry.Rect, y int, ls LineStyle, st text.Style) {
+	HLine(c, rg.Min.Shift(0, y), rg.Size().X, ls, st)
+}
+
+// VSeparator draws a vertical line across a framed range at column x,
+// relative to the range, joining the sides of the frame.
+func VSeparator(c text.Canvas, rg geometry.Rect, x int, ls LineStyle, st text.Style) {
+	VLine(c, rg.Min.Shift(x, 0), rg.Size().Y, ls, st)
+}
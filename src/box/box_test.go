@@ -0,0 +1,117 @@
+package box_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/memmaker/ECon/box"
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/text"
+)
+
+// newCanvas returns a grid of blanks.
+func newCanvas(w, h int) geometry.Grid {
+	gd := geometry.NewGrid(w, h)
+	gd.Fill(common.Cell{Char: ' ', Foreground: common.White, Background: common.Black})
+	return gd
+}
+
+func TestJoin(t *testing.T) {
+	rg := geometry.NewRect(0, 0, 5, 4)
+	st := text.Style{}
+	tests := []struct {
+		name string
+		draw func(c geometry.Grid)
+		want []string
+	}{
+		{"frame", func(c geometry.Grid) {
+			box.Frame(c, rg, box.Single, st)
+		}, []string{
+			"┌───┐",
+			"│   │",
+			"│   │",
+			"└───┘",
+		}},
+		{"separators", func(c geometry.Grid) {
+			box.Frame(c, rg, box.Single, st)
+			box.HSeparator(c, rg, 2, box.Single, st)
+			box.VSeparator(c, rg, 2, box.Single, st)
+		}, []string{
+			"┌─┬─┐",
+			"│ │ │",
+			"├─┼─┤",
+			"└─┴─┘",
+		}},
+		{"last style wins", func(c geometry.Grid) {
+			box.Frame(c, rg, box.Single, st)
+			box.HSeparator(c, rg, 1, box.Double, st)
+		}, []string{
+			"┌───┐",
+			"╠═══╣",
+			"│   │",
+			"└───┘",
+		}},
+		{"joins across styles", func(c geometry.Grid) {
+			box.Frame(c, rg, box.Double, st)
+			box.VSeparator(c, rg, 1, box.Heavy, st)
+			box.HLine(c, geometry.Point{X: 2, Y: 2}, 3, box.Rounded, st)
+		}, []string{
+			"╔┳══╗",
+			"║┃  ║",
+			"║┃──┤",
+			"╚┻══╝",
+		}},
+		// the rune of a line end is the one of a whole line, so the
+		// lines meeting it join it as a whole line
+		{"free lines", func(c geometry.Grid) {
+			box.HLine(c, geometry.Point{X: 0, Y: 1}, 5, box.Heavy, st)
+			box.VLine(c, geometry.Point{X: 2, Y: 0}, 3, box.Heavy, st)
+			box.VLine(c, geometry.Point{X: 4, Y: 1}, 3, box.Heavy, st)
+		}, []string{
+			"  ┃  ",
+			"━━╋━┳",
+			"  ┃ ┃",
+			"    ┃",
+		}},
+		{"ascii", func(c geometry.Grid) {
+			box.Frame(c, rg, box.ASCII, st)
+			box.VSeparator(c, rg, 2, box.ASCII, st)
+			box.HLine(c, geometry.Point{X: 0, Y: 2}, 2, box.ASCII, st)
+		}, []string{
+			"+-+-+",
+			"| | |",
+			"+-| |",
+			"+-+-+",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCanvas(5, 4)
+			tt.draw(c)
+			want := strings.Join(tt.want, "\n") + "\n"
+			if got := c.String(); got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestFallback(t *testing.T) {
+	hasSingle := func(r rune) bool {
+		return strings.ContainsRune(box.Single.Runes(), r)
+	}
+	tests := []struct {
+		ls, want box.LineStyle
+	}{
+		{box.Single, box.Single},
+		{box.Rounded, box.ASCII},
+		{box.Double, box.ASCII},
+		{box.ASCII, box.ASCII},
+	}
+	for _, tt := range tests {
+		if got := tt.ls.Fallback(hasSingle); got != tt.want {
+			t.Errorf("%v.Fallback() = %v, want %v", tt.ls, got, tt.want)
+		}
+	}
+}
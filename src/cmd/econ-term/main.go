@@ -0,0 +1,37 @@
+// Command econ-term runs the demo game model in a terminal with truecolor
+// support. Type Ctrl+C to quit.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"time"
+
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/ansi"
+	"github.com/memmaker/ECon/console/terminal"
+	"github.com/memmaker/ECon/game"
+)
+
+var colors256 = flag.Bool("256", false, "use the 256 color palette instead of 24-bit colors")
+
+func main() {
+	flag.Parse()
+	config := console.GridConfig{
+		TileWidth:  1,
+		TileHeight: 1,
+		GridWidth:  64,
+		GridHeight: 36,
+	}
+	mode := ansi.TrueColor
+	if *colors256 {
+		mode = ansi.Color256
+	}
+	engine := terminal.NewEngine(config, os.Stdin, os.Stdout, mode)
+	model := game.NewModel(config)
+	model.Init(engine)
+	if err := engine.Run(model, time.Second/30); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -27,6 +27,9 @@ func (c Cell) WithForegroundColor(rgbColor RGBColor) Cell {
 }
 
 func init() {
-	gob.Register(&RGBColor{})
-	gob.Register(&HSVColor{})
+	gob.Register(RGBColor{})
+	gob.Register(HSVColor{})
+	gob.Register(color.RGBA{})
+	gob.Register(color.RGBA64{})
+	gob.Register(color.NRGBA64{})
 }
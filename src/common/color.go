@@ -70,6 +70,13 @@ func (R RGBColor) LightnessScaledToneMapping() (r, g, b, a uint32) {
 	return r, g, b, a
 }
 
+// WithAlpha returns the tone mapped color with an opacity in [0, 1], for
+// blending with common.AlphaBlend, for example in console layers.
+func (R RGBColor) WithAlpha(alpha float64) color.Color {
+	r, g, b, _ := R.RGBA()
+	return color.NRGBA64{R: uint16(r), G: uint16(g), B: uint16(b), A: uint16(Clamp(alpha, 0, 1) * 0xffff)}
+}
+
 // Luma() is gamma-compressed
 func (R RGBColor) Luma() float64 {
 	return 0.2126*R.R + 0.7152*R.G + 0.0722*R.B
@@ -0,0 +1,178 @@
+// Package ansi encodes console cells as ANSI escape sequences, for terminals
+// and terminal recordings.
+package ansi
+
+import (
+	"image/color"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// ColorMode selects the SGR sequences used for the colors.
+type ColorMode int
+
+const (
+	TrueColor ColorMode = iota // 24-bit colors
+	Color256                   // xterm 256 color palette
+)
+
+// Some escape sequences that are useful together with an Encoder.
+const (
+	ClearScreen = "\x1b[2J"
+	HideCursor  = "\x1b[?25l"
+	ShowCursor  = "\x1b[?25h"
+	ResetStyle  = "\x1b[0m"
+)
+
+// Encoder appends the escape sequences drawing cells to a buffer. It keeps
+// track of the cursor position and the current colors, so that consecutive
+// cells on a line or with the same colors don't repeat sequences.
+type Encoder struct {
+	Mode ColorMode
+
+	cursor     geometry.Point
+	fg, bg     [3]uint8
+	knowCursor bool
+	knowStyle  bool
+}
+
+// Reset makes the encoder forget the cursor position and the colors, for
+// example after something else has been written to the terminal.
+func (e *Encoder) Reset() {
+	e.knowCursor = false
+	e.knowStyle = false
+}
+
+// AppendFrame appends the sequences drawing all the cells of a frame.
+func (e *Encoder) AppendFrame(buf []byte, frame console.Frame) []byte {
+	for _, cellAt := range frame.Cells {
+		buf = e.AppendCell(buf, cellAt.P, cellAt.Cell)
+	}
+	return buf
+}
+
+// AppendCell appends the sequences drawing a cell at the given position.
+func (e *Encoder) AppendCell(buf []byte, p geometry.Point, cell common.Cell) []byte {
+	if !e.knowCursor || e.cursor != p {
+		buf = AppendMoveCursor(buf, p)
+	}
+	fg := RGB8(cell.Foreground, common.White)
+	bg := RGB8(cell.Background, common.Black)
+	if !e.knowStyle || fg != e.fg || bg != e.bg {
+		buf = e.appendColors(buf, fg, bg)
+		e.fg, e.bg = fg, bg
+		e.knowStyle = true
+	}
+	char := cell.Char
+	if char < ' ' || char == utf8.RuneError {
+		char = ' '
+	}
+	buf = utf8.AppendRune(buf, char)
+	e.cursor = p.Shift(1, 0)
+	e.knowCursor = true
+	return buf
+}
+
+func (e *Encoder) appendColors(buf []byte, fg, bg [3]uint8) []byte {
+	switch e.Mode {
+	case Color256:
+		buf = append(buf, "\x1b[38;5;"...)
+		buf = strconv.AppendInt(buf, int64(Index256(fg)), 10)
+		buf = append(buf, ";48;5;"...)
+		buf = strconv.AppendInt(buf, int64(Index256(bg)), 10)
+	default:
+		buf = append(buf, "\x1b[38;2;"...)
+		buf = appendRGB(buf, fg)
+		buf = append(buf, ";48;2;"...)
+		buf = appendRGB(buf, bg)
+	}
+	return append(buf, 'm')
+}
+
+func appendRGB(buf []byte, c [3]uint8) []byte {
+	buf = strconv.AppendInt(buf, int64(c[0]), 10)
+	buf = append(buf, ';')
+	buf = strconv.AppendInt(buf, int64(c[1]), 10)
+	buf = append(buf, ';')
+	return strconv.AppendInt(buf, int64(c[2]), 10)
+}
+
+// AppendMoveCursor appends the sequence moving the cursor to the given
+// 0-based position.
+func AppendMoveCursor(buf []byte, p geometry.Point) []byte {
+	buf = append(buf, "\x1b["...)
+	buf = strconv.AppendInt(buf, int64(p.Y+1), 10)
+	buf = append(buf, ';')
+	buf = strconv.AppendInt(buf, int64(p.X+1), 10)
+	return append(buf, 'H')
+}
+
+// RGB8 returns the 8-bit channels of a color. For common.RGBColor, this
+// includes the tone mapping done by its RGBA method. A nil color is replaced by
+// the given default.
+func RGB8(c color.Color, def color.Color) [3]uint8 {
+	if c == nil {
+		c = def
+	}
+	r, g, b, _ := c.RGBA()
+	return [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)}
+}
+
+// cubeLevels are the channel values of the 6x6x6 color cube of the xterm
+// palette.
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// Index256 returns the index of the closest color of the xterm 256 color
+// palette, among the color cube and the gray ramp.
+func Index256(c [3]uint8) uint8 {
+	var cube [3]int
+	var cubeColor [3]int
+	for i, v := range c {
+		cube[i] = nearestLevel(int(v))
+		cubeColor[i] = cubeLevels[cube[i]]
+	}
+	cubeIndex := 16 + 36*cube[0] + 6*cube[1] + cube[2]
+
+	avg := (int(c[0]) + int(c[1]) + int(c[2])) / 3
+	grayStep := (avg - 3) / 10
+	if grayStep < 0 {
+		grayStep = 0
+	} else if grayStep > 23 {
+		grayStep = 23
+	}
+	gray := 8 + 10*grayStep
+	grayIndex := 232 + grayStep
+
+	if distance(c, [3]int{gray, gray, gray}) < distance(c, cubeColor) {
+		return uint8(grayIndex)
+	}
+	return uint8(cubeIndex)
+}
+
+func nearestLevel(v int) int {
+	best := 0
+	for i, level := range cubeLevels {
+		if abs(level-v) < abs(cubeLevels[best]-v) {
+			best = i
+		}
+	}
+	return best
+}
+
+func distance(c [3]uint8, d [3]int) int {
+	dr := int(c[0]) - d[0]
+	dg := int(c[1]) - d[1]
+	db := int(c[2]) - d[2]
+	return dr*dr + dg*dg + db*db
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -1,13 +1,7 @@
 package console
 
 import (
-	"embed"
-	"log"
-	"math"
-
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/vector"
-	"github.com/tinne26/etxt"
+	"io"
 
 	"github.com/memmaker/ECon/common"
 	"github.com/memmaker/ECon/geometry"
@@ -26,30 +20,50 @@ type GridConfig struct {
 	TileHeight int
 	GridWidth  int
 	GridHeight int
+	RenderMode RenderMode
 }
 
+// RenderMode selects how the graphical renderers draw the runes of the cells.
+type RenderMode int
+
+const (
+	// FontRendering draws the runes as glyphs of TrueType fonts.
+	FontRendering RenderMode = iota
+	// TilesetRendering draws the runes as tiles of a bitmap atlas, see the
+	// tileset package. Runes without a tile are drawn with the fonts, if any.
+	TilesetRendering
+)
+
+// Console is the backend-agnostic implementation of CellInterface. It keeps
+// the grid the model draws into, computes the delta frames on Flush and
+// passes them to its Renderer.
 type Console struct {
-	// basics
-	screenDPIScale float64
-	TileWidth      int
-	TileHeight     int
-	// fonts
-	txtRenderer *etxt.Renderer
-	currentFont *etxt.Font
+	renderer Renderer
 	// delta drawing
-	drawGrid            geometry.Grid
-	deltaGrid           geometry.Grid
-	frameIsDirty        bool
-	clearBeforeNextDraw bool
-
-	nextFrame Frame
+	drawGrid             geometry.Grid
+	halfGrid             geometry.Grid // allocated by HalfWidth
+	delta                frameDelta
+	halfDelta            frameDelta
+	clearBeforeNextFlush bool
+	// layers
+	layers      []*Layer // sorted by z
+	composeGrid geometry.Grid
+	// recording
+	recorder  *Recorder
+	recordErr error
 }
 
 func (c *Console) Size() geometry.Point {
 	return c.drawGrid.Size()
 }
+
+// Grid returns the grid written by Set. Drawing into slices of it, for example
+// the panels of a layout, is the same as calling Set. Resize replaces it.
+func (c *Console) Grid() geometry.Grid {
+	return c.drawGrid
+}
+
 func (c *Console) Set(p geometry.Point, cell common.Cell) {
-	//println("set", p.String(), cell.Char)
 	c.drawGrid.Set(p, cell)
 }
 
@@ -57,67 +71,19 @@ func (c *Console) At(p geometry.Point) common.Cell {
 	return c.drawGrid.At(p)
 }
 
-func NewConsole(config GridConfig) *Console {
+func NewConsole(config GridConfig, renderer Renderer) *Console {
 	return &Console{
-		TileWidth:   config.TileWidth,
-		TileHeight:  config.TileHeight,
-		txtRenderer: NewTextRenderer(),
-		drawGrid:    geometry.NewGrid(config.GridWidth, config.GridHeight),
+		renderer:  renderer,
+		drawGrid:  geometry.NewGrid(config.GridWidth, config.GridHeight),
+		delta:     frameDelta{blank: common.Cell{Char: ' ', Foreground: common.White, Background: common.Black}},
+		halfDelta: frameDelta{blank: TransparentCell, isHalfWidth: true},
 	}
 }
 
-func (c *Console) SetFont(font *etxt.Font) {
-	c.txtRenderer.SetFont(font)
-	c.currentFont = font
-}
 func (c *Console) ClearScreen() {
 	c.drawGrid.Fill(common.Cell{Char: ' ', Foreground: common.White, Background: common.Black})
-	//c.nextFrame = Frame{}
-	c.clearBeforeNextDraw = true
-	c.frameIsDirty = true
-}
-func (c *Console) Draw(screen *ebiten.Image) {
-	if !c.frameIsDirty || c.currentFont == nil {
-		return
-	}
-	if c.clearBeforeNextDraw {
-		c.clearBeforeNextDraw = false
-		screen.Fill(common.Black)
-	}
-	//println("drawing")
-	scale := c.screenDPIScale
-	tilewidth := int(math.Ceil(float64(c.TileWidth) * scale))
-	tileheight := int(math.Ceil(float64(c.TileHeight) * scale))
-
-	c.drawGrid.Iter(func(p geometry.Point, cell common.Cell) {
-		vector.DrawFilledRect(screen, float32((p.X)*tilewidth), float32((p.Y)*tileheight), float32(tilewidth), float32(tileheight), cell.Background)
-	})
-	/*
-		for _, cellAt := range c.nextFrame.Cells {
-			vector.DrawFilledRect(screen, float32((cellAt.P.X)*tilewidth), float32((cellAt.P.Y)*tileheight), float32(tilewidth), float32(tileheight), cellAt.Cell.Background)
-		}*/
-
-	c.txtRenderer.SetTarget(screen)
-	c.txtRenderer.SetSizePx(int(math.Ceil(float64(c.TileHeight) * scale)))
-	c.drawGrid.Iter(func(p geometry.Point, cell common.Cell) {
-		glyph := string(cell.Char)
-		runes, _ := etxt.GetMissingRunes(c.currentFont, glyph)
-		if len(runes) > 0 {
-			glyph = " "
-		}
-		xPos := (p.X) * tilewidth
-		yPos := (p.Y) * tileheight
-		c.txtRenderer.SetColor(cell.Foreground)
-		c.txtRenderer.Draw(glyph, xPos, yPos)
-	})
-	c.frameIsDirty = false
-}
-
-func (c *Console) SetScale(scale float64) {
-	if scale != c.screenDPIScale {
-		c.screenDPIScale = scale
-		c.ClearScreen()
-	}
+	c.halfGrid.Fill(TransparentCell)
+	c.clearBeforeNextFlush = true
 }
 
 // Flush will compute the delta between the last frame and the current frame and
@@ -127,35 +93,93 @@ func (c *Console) Flush() {
 	c.computeAndRecordNextFrame()
 }
 
+// StartRecording starts writing the flushed frames to w, see Recorder. The
+// first recorded frame contains the whole grid.
+func (c *Console) StartRecording(w io.Writer) error {
+	recorder, err := NewRecorder(w, c.drawGrid.Size(), DefaultKeyframeInterval)
+	if err != nil {
+		return err
+	}
+	c.recorder = recorder
+	c.recordErr = nil
+	c.clearBeforeNextFlush = true
+	return nil
+}
+
+// StopRecording stops the recording started with StartRecording. It returns
+// the first error encountered while writing frames, if any.
+func (c *Console) StopRecording() error {
+	if c.recorder == nil {
+		return nil
+	}
+	err := c.recorder.Close()
+	if c.recordErr != nil {
+		err = c.recordErr
+	}
+	c.recorder = nil
+	c.recordErr = nil
+	return err
+}
+
+// IsRecording reports whether frames are being recorded.
+func (c *Console) IsRecording() bool {
+	return c.recorder != nil
+}
+
 func (c *Console) Fill(rect geometry.Rect, cell common.Cell) {
 	c.drawGrid.Slice(rect).Fill(cell)
 }
 
-// computeAndRecordNextFrame will compute the delta between the last frame and the current frame and trigger a new tick for the recording.
+// computeAndRecordNextFrame will compute the delta between the last frame and
+// the current frame, for the square and the half-width grids, hand them to the
+// renderer and record them.
 func (c *Console) computeAndRecordNextFrame() {
-	forceRedrawOfAllCells := c.clearBeforeNextDraw
-	frame := c.computeFrame(c.drawGrid, forceRedrawOfAllCells)
-	if len(frame.Cells) > 0 {
-		c.frameIsDirty = true
+	forceRedrawOfAllCells := c.clearBeforeNextFlush
+	c.clearBeforeNextFlush = false
+	c.emit(c.delta.computeFrame(c.composite(), forceRedrawOfAllCells))
+	c.emit(c.halfDelta.computeFrame(c.halfGrid, forceRedrawOfAllCells))
+}
+
+func (c *Console) emit(frame Frame) {
+	if len(frame.Cells) == 0 {
+		return
+	}
+	if c.renderer != nil {
+		c.renderer.Render(frame)
+	}
+	if c.recorder != nil && c.recordErr == nil {
+		c.recordErr = c.recorder.Record(frame)
 	}
 }
 
-func (c *Console) computeFrame(gd geometry.Grid, exposed bool) Frame {
+// frameDelta computes the frames of a grid by comparing its content with the
+// one it had at the previous computation.
+type frameDelta struct {
+	deltaGrid   geometry.Grid
+	nextFrame   Frame
+	blank       common.Cell // content of the grid before the first frame
+	isHalfWidth bool
+}
+
+func (d *frameDelta) computeFrame(gd geometry.Grid, exposed bool) Frame {
 	if gd.Ug == nil || gd.Rg.Empty() && !exposed {
 		return Frame{}
 	}
-	if c.deltaGrid.Ug == nil {
-		c.deltaGrid = geometry.NewGrid(gd.Ug.Width, gd.Ug.Height)
-	} else if c.deltaGrid.Ug.Width != gd.Ug.Width || c.deltaGrid.Ug.Height != gd.Ug.Height {
-		c.deltaGrid = c.deltaGrid.Resize(gd.Ug.Width, gd.Ug.Height)
+	if d.deltaGrid.Ug == nil || d.deltaGrid.Ug.Width != gd.Ug.Width || d.deltaGrid.Ug.Height != gd.Ug.Height {
+		// the cells are compared by index in the underlying grids, which
+		// must have the same size: Grid.Resize would keep a larger one
+		d.deltaGrid = geometry.NewGrid(gd.Ug.Width, gd.Ug.Height)
+		d.deltaGrid.Fill(d.blank)
 	}
-	c.nextFrame.Cells = c.nextFrame.Cells[:0]
+	d.nextFrame.Cells = d.nextFrame.Cells[:0]
+	d.nextFrame.IsHalfWidth = d.isHalfWidth
+	d.nextFrame.IsFullRedraw = exposed
 	if exposed {
-		return c.refresh(gd)
+		return d.refresh(gd)
 	}
 	w := gd.Ug.Width
 	cells := gd.Ug.Cells
-	pcells := c.deltaGrid.Ug.Cells // previous cells
+	pcells := d.deltaGrid.Ug.Cells // previous cells
 	yimax := gd.Rg.Max.Y * w
 	for y, yi := 0, gd.Rg.Min.Y*w; yi < yimax; y, yi = y+1, yi+w {
 		ximax := yi + gd.Rg.Max.X
@@ -167,50 +191,24 @@ func (c *Console) computeFrame(gd geometry.Grid, exposed bool) Frame {
 			pcells[xi] = cellAt
 			p := geometry.Point{X: x, Y: y}
 			cdraw := FrameCell{Cell: cellAt, P: p}
-			c.nextFrame.Cells = append(c.nextFrame.Cells, cdraw)
+			d.nextFrame.Cells = append(d.nextFrame.Cells, cdraw)
 		}
 	}
-	return c.nextFrame
+	return d.nextFrame
 }
 
-func (c *Console) refresh(gd geometry.Grid) Frame {
-	gd.Rg.Min = geometry.Point{}
-	gd.Rg.Max = gd.Rg.Min.Add(geometry.Point{X: gd.Ug.Width, Y: gd.Ug.Height})
-	c.deltaGrid.Copy(gd)
+// refresh returns a full redraw frame of gd. The whole underlying grid is
+// copied to the delta grid, but only the cells in the range of gd are in the
+// frame: the underlying grid is larger after a shrinking Resize.
+func (d *frameDelta) refresh(gd geometry.Grid) Frame {
+	whole := gd
+	whole.Rg.Min = geometry.Point{}
+	whole.Rg.Max = geometry.Point{X: gd.Ug.Width, Y: gd.Ug.Height}
+	d.deltaGrid.Copy(whole)
 	it := gd.Iterator()
 	for it.Next() {
 		cdraw := FrameCell{Cell: it.Cell(), P: it.P()}
-		c.nextFrame.Cells = append(c.nextFrame.Cells, cdraw)
+		d.nextFrame.Cells = append(d.nextFrame.Cells, cdraw)
 	}
-	return c.nextFrame
-}
-
-func (c *Console) LoadEmbeddedFont(fontDir string, fontName string, fs embed.FS) {
-	fontLib := etxt.NewFontLibrary()
-	_, _, err := fontLib.ParseEmbedDirFonts(fontDir, fs)
-	if err != nil {
-		log.Fatalf("Error while loading EmbeddedData: %s", err.Error())
-	}
-
-	// check that we have the EmbeddedData we want
-	// (shown for completeness, you don't need this in most cases)
-	expectedFonts := []string{fontName}
-	for _, expectedFontName := range expectedFonts {
-		if !fontLib.HasFont(expectedFontName) {
-			log.Fatal("missing expectedFontName: " + expectedFontName)
-		}
-	}
-	loadedFont := fontLib.GetFont(expectedFonts[0])
-	c.SetFont(loadedFont)
-
-}
-
-func NewTextRenderer() *etxt.Renderer {
-	txtRenderer := etxt.NewStdRenderer()
-	glyphsCache := etxt.NewDefaultCache(10 * 1024 * 1024) // 10MB
-	txtRenderer.SetCacheHandler(glyphsCache.NewHandler())
-	txtRenderer.SetAlign(etxt.Top, etxt.Left)
-	whiteColor := common.White
-	txtRenderer.SetColor(whiteColor)
-	return txtRenderer
+	return d.nextFrame
 }
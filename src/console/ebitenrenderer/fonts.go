@@ -0,0 +1,107 @@
+package ebitenrenderer
+
+import (
+	"sort"
+
+	"github.com/tinne26/etxt"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// noFont is the cached font index of the runes that no font covers.
+const noFont = -1
+
+// fontChain is an ordered list of fonts: a rune is drawn with the first font
+// that has a glyph for it. The font chosen for each rune is cached, so the
+// coverage is only checked once per rune. The zero value is an empty chain.
+type fontChain struct {
+	fonts     []*etxt.Font
+	runeFonts map[rune]int // rune -> index in fonts, or noFont
+}
+
+func (fc *fontChain) set(fonts []*etxt.Font) {
+	fc.fonts = fonts
+	fc.runeFonts = make(map[rune]int)
+}
+
+// fontFor returns the index of the font used to draw a rune, or noFont.
+func (fc *fontChain) fontFor(r rune) int {
+	if index, ok := fc.runeFonts[r]; ok {
+		return index
+	}
+	index := noFont
+	for i, font := range fc.fonts {
+		missing, err := etxt.GetMissingRunes(font, string(r))
+		if err == nil && len(missing) == 0 {
+			index = i
+			break
+		}
+	}
+	if fc.runeFonts == nil {
+		fc.runeFonts = make(map[rune]int)
+	}
+	fc.runeFonts[r] = index
+	return index
+}
+
+// Fonts returns the fallback chain set by SetFonts, for example to paint
+// screenshots with offscreen.NewFallbackPainter.
+func (r *Renderer) Fonts() []*etxt.Font {
+	return r.fonts.fonts
+}
+
+// FontFor returns the font of the fallback chain used to draw a rune, or nil
+// if no font covers it.
+func (r *Renderer) FontFor(char rune) *etxt.Font {
+	index := r.fonts.fontFor(char)
+	if index == noFont {
+		return nil
+	}
+	return r.fonts.fonts[index]
+}
+
+// MissingRunes returns the sorted runes of the given texts that no font of
+// the fallback chain covers, nor the tileset in the TilesetRendering mode.
+// Those runes are drawn as a space. It is meant to be called at startup with
+// the texts used by the UI, to diagnose missing glyphs early.
+func (r *Renderer) MissingRunes(texts ...string) []rune {
+	seen := make(map[rune]bool)
+	for _, text := range texts {
+		for _, char := range text {
+			seen[char] = true
+		}
+	}
+	return r.missingOf(seen)
+}
+
+// MissingRunesInGrid is like MissingRunes, for the runes of a grid, for
+// example a map drawn into a grid.
+func (r *Renderer) MissingRunesInGrid(gd geometry.Grid) []rune {
+	seen := make(map[rune]bool)
+	gd.Iter(func(p geometry.Point, cell common.Cell) {
+		seen[cell.Char] = true
+	})
+	return r.missingOf(seen)
+}
+
+func (r *Renderer) missingOf(runes map[rune]bool) []rune {
+	var missing []rune
+	for char := range runes {
+		if char == 0 || char == ' ' {
+			continue
+		}
+		if r.usesTiles() {
+			if _, ok := r.tileset.Tile(char); ok {
+				continue
+			}
+		}
+		if r.fonts.fontFor(char) == noFont {
+			missing = append(missing, char)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i] < missing[j]
+	})
+	return missing
+}
@@ -0,0 +1,255 @@
+package ebitenrenderer
+
+import (
+	"embed"
+	"log"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"github.com/tinne26/etxt"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/tileset"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// Renderer is the console.Renderer that paints the frames onto an ebiten
+// screen using etxt for the glyphs.
+type Renderer struct {
+	// basics
+	screenDPIScale float64
+	TileWidth      int
+	TileHeight     int
+	// fonts
+	txtRenderer *etxt.Renderer
+	fonts       fontChain
+	currentFont int // index in fonts of the font set on txtRenderer
+	// tiles
+	renderMode console.RenderMode
+	tileset    *tileset.Tileset
+	tiles      []*ebiten.Image // sub-images of the atlas, by tile index
+	// delta drawing
+	screen     *console.Screen // content of all the frames rendered so far
+	repaintAll bool
+
+	dirtyCells []geometry.Point // square cells changed by the frames rendered since the last Draw
+	dirtyIndex []bool           // grid index -> whether the cell is in dirtyCells
+}
+
+func NewRenderer(config console.GridConfig) *Renderer {
+	return &Renderer{
+		TileWidth:   config.TileWidth,
+		TileHeight:  config.TileHeight,
+		txtRenderer: NewTextRenderer(),
+		currentFont: noFont,
+		renderMode:  config.RenderMode,
+		screen:      console.NewScreen(geometry.Point{X: config.GridWidth, Y: config.GridHeight}),
+		dirtyIndex:  make([]bool, config.GridWidth*config.GridHeight),
+		repaintAll:  true,
+	}
+}
+
+func (r *Renderer) SetFont(font *etxt.Font) {
+	r.SetFonts(font)
+}
+
+// SetFonts sets an ordered list of fonts forming a fallback chain: each rune
+// is drawn with the first font that covers it.
+func (r *Renderer) SetFonts(fonts ...*etxt.Font) {
+	r.fonts.set(fonts)
+	r.currentFont = noFont
+	r.repaintAll = true
+}
+
+// SetTileset sets the atlas used in the TilesetRendering mode. Its tiles are
+// scaled to the tile size of the grid.
+func (r *Renderer) SetTileset(ts *tileset.Tileset) {
+	r.tileset = ts
+	atlas := ebiten.NewImageFromImage(ts.Image)
+	r.tiles = make([]*ebiten.Image, ts.TileCount())
+	for i := range r.tiles {
+		r.tiles[i] = atlas.SubImage(ts.TileBounds(i)).(*ebiten.Image)
+	}
+	r.repaintAll = true
+}
+
+// Render implements console.Renderer. It records the changed cells, they will
+// be painted by the next Draw.
+func (r *Renderer) Render(frame console.Frame) {
+	if frame.IsFullRedraw {
+		r.repaintAll = true
+	}
+	if r.repaintAll {
+		r.screen.Apply(frame, nil)
+		return
+	}
+	r.screen.Apply(frame, r.markDirty)
+}
+
+// Draw paints the frames rendered since the last call onto the screen. Only
+// the cells that changed are repainted, unless a full repaint was requested by
+// a full redraw frame or SetScale.
+func (r *Renderer) Draw(screen *ebiten.Image) {
+	if len(r.fonts.fonts) == 0 && !r.usesTiles() || !r.repaintAll && len(r.dirtyCells) == 0 {
+		return
+	}
+	tileWidth := int(math.Ceil(float64(r.TileWidth) * r.screenDPIScale))
+	tileHeight := int(math.Ceil(float64(r.TileHeight) * r.screenDPIScale))
+	r.txtRenderer.SetTarget(screen)
+	r.txtRenderer.SetSizePx(tileHeight)
+
+	if r.repaintAll {
+		screen.Fill(common.Black)
+		r.screen.Square.Range().Iter(func(p geometry.Point) {
+			r.drawBackground(screen, p, tileWidth, tileHeight)
+		})
+		r.screen.Square.Range().Iter(func(p geometry.Point) {
+			r.drawGlyph(screen, p, tileWidth, tileHeight)
+		})
+		r.repaintAll = false
+	} else {
+		for _, p := range r.dirtyCells {
+			r.drawBackground(screen, p, tileWidth, tileHeight)
+		}
+		for _, p := range r.dirtyCells {
+			r.drawGlyph(screen, p, tileWidth, tileHeight)
+		}
+	}
+	r.clearDirtyCells()
+}
+
+// drawBackground paints the background of a square cell, or the backgrounds
+// of its two half-width cells.
+func (r *Renderer) drawBackground(screen *ebiten.Image, p geometry.Point, tileWidth, tileHeight int) {
+	x, y := float32(p.X*tileWidth), float32(p.Y*tileHeight)
+	if !r.screen.HasHalfWidth(p) {
+		cell := r.screen.Square.At(p)
+		vector.DrawFilledRect(screen, x, y, float32(tileWidth), float32(tileHeight), cell.Background)
+		return
+	}
+	halfWidth := float32(tileWidth) / 2
+	left, right := r.screen.Halves(p)
+	vector.DrawFilledRect(screen, x, y, halfWidth, float32(tileHeight), left.Background)
+	vector.DrawFilledRect(screen, x+halfWidth, y, float32(tileWidth)-halfWidth, float32(tileHeight), right.Background)
+}
+
+// drawGlyph draws the glyph of a square cell, or the glyphs of its two
+// half-width cells.
+func (r *Renderer) drawGlyph(screen *ebiten.Image, p geometry.Point, tileWidth, tileHeight int) {
+	x, y := p.X*tileWidth, p.Y*tileHeight
+	if !r.screen.HasHalfWidth(p) {
+		r.drawRune(screen, r.screen.Square.At(p), x, y, tileWidth, tileHeight)
+		return
+	}
+	left, right := r.screen.Halves(p)
+	r.drawRune(screen, left, x, y, tileWidth/2, tileHeight)
+	r.drawRune(screen, right, x+tileWidth/2, y, tileWidth-tileWidth/2, tileHeight)
+}
+
+func (r *Renderer) drawRune(screen *ebiten.Image, cell common.Cell, x, y, width, height int) {
+	if r.usesTiles() && r.drawTile(screen, cell, x, y, width, height) {
+		return
+	}
+	index := r.fonts.fontFor(cell.Char)
+	if index == noFont {
+		return // drawn as a space
+	}
+	if index != r.currentFont {
+		r.txtRenderer.SetFont(r.fonts.fonts[index])
+		r.currentFont = index
+	}
+	r.txtRenderer.SetColor(cell.Foreground)
+	r.txtRenderer.Draw(string(cell.Char), x, y)
+}
+
+func (r *Renderer) usesTiles() bool {
+	return r.renderMode == console.TilesetRendering && r.tileset != nil
+}
+
+// drawTile draws the tile of a cell, tinted with its foreground color, and
+// reports false if the rune has no tile.
+func (r *Renderer) drawTile(screen *ebiten.Image, cell common.Cell, x, y, width, height int) bool {
+	index, ok := r.tileset.Tile(cell.Char)
+	if !ok {
+		return false
+	}
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(float64(width)/float64(r.tileset.TileWidth), float64(height)/float64(r.tileset.TileHeight))
+	op.GeoM.Translate(float64(x), float64(y))
+	op.ColorScale.ScaleWithColor(cell.Foreground)
+	screen.DrawImage(r.tiles[index], op)
+	return true
+}
+
+// Resize implements console.Resizer. The whole grid will be repainted on the
+// next Draw.
+func (r *Renderer) Resize(size geometry.Point) {
+	r.screen.Resize(size)
+	r.dirtyCells = r.dirtyCells[:0]
+	r.dirtyIndex = make([]bool, size.X*size.Y)
+	r.repaintAll = true
+}
+
+// SetScale changes the device scale used for drawing. The whole grid will be
+// repainted on the next Draw.
+func (r *Renderer) SetScale(scale float64) {
+	if scale != r.screenDPIScale {
+		r.screenDPIScale = scale
+		r.repaintAll = true
+	}
+}
+
+// markDirty adds a square cell to the set of cells that the next Draw has to
+// repaint.
+func (r *Renderer) markDirty(p geometry.Point) {
+	if !r.screen.Square.Contains(p) {
+		return
+	}
+	// the underlying grid keeps its width when the screen shrinks
+	i := p.Y*r.screen.Size().X + p.X
+	if r.dirtyIndex[i] {
+		return
+	}
+	r.dirtyCells = append(r.dirtyCells, p)
+	r.dirtyIndex[i] = true
+}
+
+func (r *Renderer) clearDirtyCells() {
+	w := r.screen.Size().X
+	for _, p := range r.dirtyCells {
+		r.dirtyIndex[p.Y*w+p.X] = false
+	}
+	r.dirtyCells = r.dirtyCells[:0]
+}
+
+// LoadEmbeddedFont loads the fonts of an embedded directory and uses the
+// fonts with the given names as fallback chain, in that order.
+func (r *Renderer) LoadEmbeddedFont(fontDir string, fontNames []string, fs embed.FS) {
+	fontLib := etxt.NewFontLibrary()
+	_, _, err := fontLib.ParseEmbedDirFonts(fontDir, fs)
+	if err != nil {
+		log.Fatalf("Error while loading EmbeddedData: %s", err.Error())
+	}
+
+	// check that we have the EmbeddedData we want
+	loadedFonts := make([]*etxt.Font, 0, len(fontNames))
+	for _, expectedFontName := range fontNames {
+		if !fontLib.HasFont(expectedFontName) {
+			log.Fatal("missing expectedFontName: " + expectedFontName)
+		}
+		loadedFonts = append(loadedFonts, fontLib.GetFont(expectedFontName))
+	}
+	r.SetFonts(loadedFonts...)
+}
+
+func NewTextRenderer() *etxt.Renderer {
+	txtRenderer := etxt.NewStdRenderer()
+	glyphsCache := etxt.NewDefaultCache(10 * 1024 * 1024) // 10MB
+	txtRenderer.SetCacheHandler(glyphsCache.NewHandler())
+	txtRenderer.SetAlign(etxt.Top, etxt.Left)
+	whiteColor := common.White
+	txtRenderer.SetColor(whiteColor)
+	return txtRenderer
+}
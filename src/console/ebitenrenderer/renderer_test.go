@@ -0,0 +1,119 @@
+package ebitenrenderer
+
+import (
+	"os"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/tinne26/etxt"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// TestMain runs the tests while an ebiten game loop is running, ebiten
+// images need it to be drawn. It needs a display.
+func TestMain(m *testing.M) {
+	code := 0
+	game := &testGame{done: make(chan struct{})}
+	go func() {
+		code = m.Run()
+		close(game.done)
+	}()
+	if err := ebiten.RunGame(game); err != nil {
+		panic(err)
+	}
+	os.Exit(code)
+}
+
+type testGame struct {
+	done chan struct{}
+}
+
+func (g *testGame) Update() error {
+	select {
+	case <-g.done:
+		return ebiten.Termination
+	default:
+		return nil
+	}
+}
+
+func (g *testGame) Draw(screen *ebiten.Image) {}
+
+func (g *testGame) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return 64, 36
+}
+
+// benchmarkDraw measures a Draw after a single changed cell on a 64x36
+// grid, with the delta repaint or with a full repaint.
+func benchmarkDraw(b *testing.B, repaintAll bool) {
+	font, _, err := etxt.ParseFontFrom("../../embedded/font/square.ttf")
+	if err != nil {
+		b.Fatal(err)
+	}
+	config := console.GridConfig{TileWidth: 20, TileHeight: 20, GridWidth: 64, GridHeight: 36}
+	r := NewRenderer(config)
+	r.SetFont(font)
+	r.SetScale(1)
+	con := console.NewConsole(config, r)
+	geometry.NewRect(0, 0, config.GridWidth, config.GridHeight).Iter(func(p geometry.Point) {
+		con.Set(p, common.Cell{Char: rune('a' + (p.X+p.Y)%26), Foreground: common.White, Background: common.Black})
+	})
+	con.Flush()
+	screen := ebiten.NewImage(config.GridWidth*config.TileWidth, config.GridHeight*config.TileHeight)
+	r.Draw(screen)
+	changed := geometry.Point{X: 10, Y: 10}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		con.Set(changed, common.Cell{Char: rune('A' + i%26), Foreground: common.White, Background: common.Black})
+		con.Flush()
+		r.repaintAll = repaintAll
+		r.Draw(screen)
+	}
+}
+
+func BenchmarkDrawDelta(b *testing.B) {
+	benchmarkDraw(b, false)
+}
+
+func BenchmarkDrawFull(b *testing.B) {
+	benchmarkDraw(b, true)
+}
+
+// TestResizeShrinkDelta checks that the delta frames after a shrinking resize
+// are drawn: the underlying grid of the screen keeps its previous width.
+func TestResizeShrinkDelta(t *testing.T) {
+	font, _, err := etxt.ParseFontFrom("../../embedded/font/square.ttf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := console.GridConfig{TileWidth: 20, TileHeight: 20, GridWidth: 64, GridHeight: 36}
+	r := NewRenderer(config)
+	r.SetFont(font)
+	r.SetScale(1)
+	con := console.NewConsole(config, r)
+	con.ClearScreen()
+	con.Flush()
+	screen := ebiten.NewImage(config.GridWidth*config.TileWidth, config.GridHeight*config.TileHeight)
+	r.Draw(screen)
+
+	con.Resize(geometry.Point{X: 63, Y: 36})
+	con.Flush()
+	r.Draw(screen)
+	p := geometry.Point{X: 62, Y: 35}
+	cell := common.Cell{Char: 'x', Foreground: common.White, Background: common.Black}
+	con.Set(p, cell)
+	con.Flush()
+	if len(r.dirtyCells) != 1 || r.dirtyCells[0] != p {
+		t.Fatalf("dirty cells = %v, want [%v]", r.dirtyCells, p)
+	}
+	r.Draw(screen)
+	if len(r.dirtyCells) != 0 {
+		t.Errorf("dirty cells after Draw = %v, want none", r.dirtyCells)
+	}
+	if got := r.screen.Square.At(p); got != cell {
+		t.Errorf("screen cell %v = %q, want %q", p, got.Char, cell.Char)
+	}
+}
@@ -7,3 +7,17 @@ import (
 type Engine interface {
 	GetInput() input.GridInput
 }
+
+// Model is implemented by the types driven by the game loop, like
+// game.Model: Update is called once per frame, followed by Draw and a Flush of
+// the console.
+type Model interface {
+	Update(engine Engine)
+	Draw(con CellInterface)
+}
+
+// Initializer is implemented by the models that have to be initialized with
+// the engine before their first Update, like game.Model.
+type Initializer interface {
+	Init(engine Engine)
+}
@@ -0,0 +1,62 @@
+// Package export converts console recordings to formats that can be shared
+// outside the game: asciinema casts, animated GIFs and PNG sequences.
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/ansi"
+	"github.com/memmaker/ECon/geometry"
+)
+
+type asciicastHeader struct {
+	Version int               `json:"version"`
+	Width   int               `json:"width"`
+	Height  int               `json:"height"`
+	Title   string            `json:"title,omitempty"`
+	Env     map[string]string `json:"env"`
+}
+
+// WriteAsciicast writes a recording as an asciicast v2 file, as played by
+// asciinema. The cells are drawn with 24-bit SGR colors. Like in the terminal
+// backend, a square cell takes two terminal columns.
+func WriteAsciicast(w io.Writer, rec *console.Recording, title string) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	err := enc.Encode(asciicastHeader{
+		Version: 2,
+		Width:   2 * rec.Width,
+		Height:  rec.Height,
+		Title:   title,
+		Env:     map[string]string{"TERM": "xterm-256color"},
+	})
+	if err != nil {
+		return err
+	}
+	encoder := ansi.Encoder{Mode: ansi.TrueColor}
+	screen := console.NewScreen(geometry.Point{X: rec.Width, Y: rec.Height})
+	var buf []byte
+	for i, recorded := range rec.Frames {
+		buf = buf[:0]
+		if i == 0 {
+			buf = append(buf, ansi.HideCursor+ansi.ClearScreen...)
+		}
+		screen.Apply(recorded.Frame, func(p geometry.Point) {
+			if !screen.Square.Contains(p) {
+				return
+			}
+			left, right := screen.Halves(p)
+			q := console.SquareToHalfWidth(p)
+			buf = encoder.AppendCell(buf, q, left)
+			buf = encoder.AppendCell(buf, q.Shift(1, 0), right)
+		})
+		event := []interface{}{recorded.Time.Seconds(), "o", string(buf)}
+		if err := enc.Encode(event); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,101 @@
+package export
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/offscreen"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// lastFrameDelay is how long the last frame of a GIF is shown before the
+// animation loops.
+const lastFrameDelay = time.Second
+
+// WriteGIF renders a recording offscreen with the given painter and writes it
+// as an animated GIF. Each recorded frame only encodes the pixels of the
+// bounding box of its changed cells. Colors are reduced to the Plan 9
+// palette.
+func WriteGIF(w io.Writer, rec *console.Recording, painter *offscreen.Painter) error {
+	size := geometry.Point{X: rec.Width, Y: rec.Height}
+	screen := console.NewScreen(size)
+	canvas := image.NewRGBA(painter.Bounds(size))
+	anim := &gif.GIF{
+		Config: image.Config{
+			ColorModel: color.Palette(palette.Plan9),
+			Width:      canvas.Bounds().Dx(),
+			Height:     canvas.Bounds().Dy(),
+		},
+	}
+	for i, recorded := range rec.Frames {
+		dirty := image.Rectangle{}
+		screen.Apply(recorded.Frame, func(p geometry.Point) {
+			screen.PaintCell(canvas, painter, p)
+			dirty = dirty.Union(painter.CellBounds(p))
+		})
+		dirty = dirty.Intersect(canvas.Bounds())
+		if dirty.Empty() {
+			dirty = image.Rect(0, 0, 1, 1)
+		}
+		frame := image.NewPaletted(dirty, palette.Plan9)
+		draw.Draw(frame, dirty, canvas, dirty.Min, draw.Src)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, gifDelay(rec, i))
+		anim.Disposal = append(anim.Disposal, gif.DisposalNone)
+	}
+	return gif.EncodeAll(w, anim)
+}
+
+// gifDelay returns the delay of the i-th frame, in 100ths of a second. Most
+// viewers don't honor delays below 2.
+func gifDelay(rec *console.Recording, i int) int {
+	d := lastFrameDelay
+	if i+1 < len(rec.Frames) {
+		d = rec.Frames[i+1].Time - rec.Frames[i].Time
+	}
+	delay := int(d / (10 * time.Millisecond))
+	if delay < 2 {
+		delay = 2
+	}
+	return delay
+}
+
+// WritePNGSequence renders a recording offscreen and writes one PNG file per
+// recorded frame into dir, named frame_00000.png, frame_00001.png and so on.
+func WritePNGSequence(dir string, rec *console.Recording, painter *offscreen.Painter) error {
+	size := geometry.Point{X: rec.Width, Y: rec.Height}
+	screen := console.NewScreen(size)
+	canvas := image.NewRGBA(painter.Bounds(size))
+	for i, recorded := range rec.Frames {
+		screen.Apply(recorded.Frame, func(p geometry.Point) {
+			screen.PaintCell(canvas, painter, p)
+		})
+		path := filepath.Join(dir, fmt.Sprintf("frame_%05d.png", i))
+		if err := writePNG(path, canvas); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
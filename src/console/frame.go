@@ -6,8 +6,9 @@ import (
 )
 
 type Frame struct {
-	Cells       []FrameCell // cells that changed from previous squareDeltaFrame
-	IsHalfWidth bool
+	Cells        []FrameCell // cells that changed from previous squareDeltaFrame
+	IsHalfWidth  bool
+	IsFullRedraw bool // Cells contains every cell of the grid
 }
 type FrameCell struct {
 	Cell common.Cell    // cell content and styling
@@ -0,0 +1,190 @@
+package console
+
+import (
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// Besides its grid of square cells, a console has a grid of half-width cells,
+// with twice as many columns: the square cell (x, y) covers the half-width
+// cells (2x, y) and (2x+1, y). Half-width cells are meant for text, for
+// example in a sidebar, while the square cells are used for the map.
+//
+// The half-width grid starts transparent (see TransparentCell). When one of
+// the two half-width cells covering a square cell is not transparent, both
+// are displayed in place of the square cell, above its background. Their
+// frames have IsHalfWidth set.
+
+// SquareToHalfWidth returns the position of the left half-width cell covered
+// by a square cell.
+func SquareToHalfWidth(p geometry.Point) geometry.Point {
+	return geometry.Point{X: 2 * p.X, Y: p.Y}
+}
+
+// HalfWidthToSquare returns the position of the square cell covering a
+// half-width cell.
+func HalfWidthToSquare(p geometry.Point) geometry.Point {
+	return geometry.Point{X: floorDiv2(p.X), Y: p.Y}
+}
+
+// SquareRectToHalfWidth returns the half-width cells covered by a range of
+// square cells.
+func SquareRectToHalfWidth(rg geometry.Rect) geometry.Rect {
+	rg.Min.X *= 2
+	rg.Max.X *= 2
+	return rg
+}
+
+// HalfWidthRectToSquare returns the smallest range of square cells covering a
+// range of half-width cells.
+func HalfWidthRectToSquare(rg geometry.Rect) geometry.Rect {
+	rg.Min.X = floorDiv2(rg.Min.X)
+	rg.Max.X = floorDiv2(rg.Max.X + 1)
+	return rg
+}
+
+func floorDiv2(x int) int {
+	if x < 0 {
+		return (x - 1) / 2
+	}
+	return x / 2
+}
+
+// HalfWidthInterface is implemented by the cell interfaces that also have a
+// half-width grid, like Console.
+type HalfWidthInterface interface {
+	HalfWidth() CellInterface
+}
+
+// HalfWidth returns the half-width grid of the console. Its Set, At and Fill
+// methods use half-width coordinates, ClearScreen makes it transparent again,
+// and Flush does nothing: it is flushed together with the console.
+func (c *Console) HalfWidth() CellInterface {
+	if c.halfGrid.Ug == nil {
+		size := SquareToHalfWidth(c.drawGrid.Size())
+		c.halfGrid = geometry.NewGrid(size.X, size.Y)
+		c.halfGrid.Fill(TransparentCell)
+	}
+	return halfWidthView{c}
+}
+
+type halfWidthView struct {
+	c *Console
+}
+
+func (v halfWidthView) Set(p geometry.Point, cell common.Cell) {
+	v.c.halfGrid.Set(p, cell)
+}
+
+func (v halfWidthView) At(p geometry.Point) common.Cell {
+	return v.c.halfGrid.At(p)
+}
+
+func (v halfWidthView) Size() geometry.Point {
+	return v.c.halfGrid.Size()
+}
+
+func (v halfWidthView) Flush() {}
+
+func (v halfWidthView) ClearScreen() {
+	v.c.halfGrid.Fill(TransparentCell)
+}
+
+func (v halfWidthView) Fill(rect geometry.Rect, cell common.Cell) {
+	v.c.halfGrid.Slice(rect).Fill(cell)
+}
+
+// Screen is the displayed content of a console, rebuilt from its frames. It
+// is used by renderers, recorders and players, so that they all display the
+// square and half-width cells the same way.
+type Screen struct {
+	Square    geometry.Grid
+	HalfWidth geometry.Grid
+}
+
+// NewScreen returns a screen of the given size in square cells.
+func NewScreen(size geometry.Point) *Screen {
+	s := &Screen{
+		Square:    geometry.NewGrid(size.X, size.Y),
+		HalfWidth: geometry.NewGrid(2*size.X, size.Y),
+	}
+	s.HalfWidth.Fill(TransparentCell)
+	return s
+}
+
+// Size returns the size of the screen in square cells.
+func (s *Screen) Size() geometry.Point {
+	return s.Square.Size()
+}
+
+// Apply updates the screen with the cells of a frame. If dirty is not nil, it
+// is called with the square positions whose display changed; a position may be
+// reported twice.
+func (s *Screen) Apply(frame Frame, dirty func(p geometry.Point)) {
+	gd := s.Square
+	if frame.IsHalfWidth {
+		gd = s.HalfWidth
+	}
+	for _, cellAt := range frame.Cells {
+		gd.Set(cellAt.P, cellAt.Cell)
+		if dirty == nil {
+			continue
+		}
+		if frame.IsHalfWidth {
+			dirty(HalfWidthToSquare(cellAt.P))
+		} else {
+			dirty(cellAt.P)
+		}
+	}
+}
+
+// Clear resets the square cells to blanks and makes the half-width cells
+// transparent.
+func (s *Screen) Clear() {
+	s.Square.Fill(common.Cell{Char: ' ', Foreground: common.White, Background: common.Black})
+	s.HalfWidth.Fill(TransparentCell)
+}
+
+// HasHalfWidth reports whether the square cell at p is displayed as two
+// half-width cells.
+func (s *Screen) HasHalfWidth(p geometry.Point) bool {
+	q := SquareToHalfWidth(p)
+	return s.HalfWidth.At(q) != TransparentCell || s.HalfWidth.At(q.Shift(1, 0)) != TransparentCell
+}
+
+// Halves returns the two half-width cells displayed at the square position p.
+// If the square cell has no half-width cells, the left one is the square cell
+// and the right one a blank with the same background. Otherwise, the
+// half-width cells are composed above the background of the square cell.
+func (s *Screen) Halves(p geometry.Point) (left, right common.Cell) {
+	square := s.Square.At(p)
+	blank := common.Cell{Char: ' ', Foreground: square.Foreground, Background: square.Background}
+	if !s.HasHalfWidth(p) {
+		return square, blank
+	}
+	q := SquareToHalfWidth(p)
+	return ComposeCell(blank, s.HalfWidth.At(q)), ComposeCell(blank, s.HalfWidth.At(q.Shift(1, 0)))
+}
+
+// Frames returns the full redraw frames of the square grid and of the
+// half-width grid, in that order. The half-width frame is omitted if all its
+// cells are transparent.
+func (s *Screen) Frames() []Frame {
+	frames := []Frame{fullFrame(s.Square, false)}
+	half := fullFrame(s.HalfWidth, true)
+	for _, cellAt := range half.Cells {
+		if cellAt.Cell != TransparentCell {
+			return append(frames, half)
+		}
+	}
+	return frames
+}
+
+func fullFrame(gd geometry.Grid, isHalfWidth bool) Frame {
+	frame := Frame{IsHalfWidth: isHalfWidth, IsFullRedraw: true}
+	it := gd.Iterator()
+	for it.Next() {
+		frame.Cells = append(frame.Cells, FrameCell{Cell: it.Cell(), P: it.P()})
+	}
+	return frame
+}
@@ -0,0 +1,84 @@
+package console
+
+import (
+	"testing"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+)
+
+func TestHalfWidthToSquare(t *testing.T) {
+	tests := []struct {
+		half, square geometry.Point
+	}{
+		{geometry.Point{X: 0, Y: 0}, geometry.Point{X: 0, Y: 0}},
+		{geometry.Point{X: 1, Y: 0}, geometry.Point{X: 0, Y: 0}},
+		{geometry.Point{X: 2, Y: 3}, geometry.Point{X: 1, Y: 3}},
+		{geometry.Point{X: 5, Y: 1}, geometry.Point{X: 2, Y: 1}},
+		{geometry.Point{X: -1, Y: 0}, geometry.Point{X: -1, Y: 0}},
+		{geometry.Point{X: -2, Y: 0}, geometry.Point{X: -1, Y: 0}},
+		{geometry.Point{X: -3, Y: 0}, geometry.Point{X: -2, Y: 0}},
+	}
+	for _, tt := range tests {
+		if got := HalfWidthToSquare(tt.half); got != tt.square {
+			t.Errorf("HalfWidthToSquare(%v) = %v, want %v", tt.half, got, tt.square)
+		}
+		left := SquareToHalfWidth(tt.square)
+		if tt.half != left && tt.half != left.Shift(1, 0) {
+			t.Errorf("SquareToHalfWidth(%v) = %v, want %v or its left neighbor", tt.square, left, tt.half)
+		}
+	}
+
+	rg := geometry.NewRect(1, 0, 4, 2)
+	if got, want := HalfWidthRectToSquare(rg), geometry.NewRect(0, 0, 2, 2); got != want {
+		t.Errorf("HalfWidthRectToSquare(%v) = %v, want %v", rg, got, want)
+	}
+	if got, want := SquareRectToHalfWidth(geometry.NewRect(0, 0, 2, 2)), geometry.NewRect(0, 0, 4, 2); got != want {
+		t.Errorf("SquareRectToHalfWidth = %v, want %v", got, want)
+	}
+}
+
+func TestHalfWidthCompositing(t *testing.T) {
+	size := geometry.Point{X: 2, Y: 1}
+	r := &screenRenderer{screen: NewScreen(size)}
+	con := NewConsole(GridConfig{GridWidth: size.X, GridHeight: size.Y}, r)
+	con.ClearScreen()
+	con.Set(geometry.Point{X: 0}, common.Cell{Char: '#', Foreground: common.White, Background: common.Black})
+	con.Set(geometry.Point{X: 1}, common.Cell{Char: '#', Foreground: common.White, Background: common.Red})
+	half := con.HalfWidth()
+	if got, want := half.Size(), (geometry.Point{X: 4, Y: 1}); got != want {
+		t.Fatalf("half-width size = %v, want %v", got, want)
+	}
+	half.Set(geometry.Point{X: 2}, common.Cell{Char: 'a', Foreground: common.Black})
+	r.frames = nil
+	con.Flush()
+	if len(r.frames) != 2 || r.frames[0].IsHalfWidth || !r.frames[1].IsHalfWidth {
+		t.Fatalf("frames = %+v, want a square frame then a half-width one", r.frames)
+	}
+
+	s := r.screen
+	if s.HasHalfWidth(geometry.Point{X: 0}) {
+		t.Error("the square cell without half-width cells is displayed as halves")
+	}
+	left, right := s.Halves(geometry.Point{X: 0})
+	if left.Char != '#' || right.Char != ' ' || right.Background != common.Black {
+		t.Errorf("Halves of a square cell = %+v, %+v, want the square cell and a blank", left, right)
+	}
+	if !s.HasHalfWidth(geometry.Point{X: 1}) {
+		t.Fatal("the square cell with a half-width cell is not displayed as halves")
+	}
+	left, right = s.Halves(geometry.Point{X: 1})
+	want := common.Cell{Char: 'a', Foreground: common.Black, Background: common.Red}
+	if left != want {
+		t.Errorf("left half = %+v, want %+v above the square background", left, want)
+	}
+	if want := (common.Cell{Char: ' ', Foreground: common.White, Background: common.Red}); right != want {
+		t.Errorf("right half = %+v, want the transparent cell as %+v", right, want)
+	}
+
+	half.ClearScreen()
+	con.Flush()
+	if s.HasHalfWidth(geometry.Point{X: 1}) {
+		t.Error("the half-width cells are still displayed after their ClearScreen")
+	}
+}
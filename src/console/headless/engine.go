@@ -0,0 +1,74 @@
+package headless
+
+import (
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/input"
+)
+
+// Engine is a console.Engine running the game loop without a window. It is
+// the headless counterpart of the ebiten Game in the main package.
+type Engine struct {
+	Input    *Input
+	Console  *console.Console
+	Renderer *Renderer
+	replay   *input.Replay // input of the model while Replay runs
+	model    console.Model // last model initialized by Step
+}
+
+func NewEngine(config console.GridConfig) *Engine {
+	renderer := NewRenderer(config)
+	return &Engine{
+		Input:    NewInput(),
+		Console:  console.NewConsole(config, renderer),
+		Renderer: renderer,
+	}
+}
+
+func (e *Engine) GetInput() input.GridInput {
+	if e.replay != nil {
+		return e.replay
+	}
+	return e.Input
+}
+
+// Step runs one iteration of the game loop: the model's Update and Draw, the
+// console Flush, and the end of the input frame.
+//
+// The first time Step runs a model implementing console.Initializer, it calls
+// its Init method before the Update, so the callers must not call it.
+func (e *Engine) Step(model console.Model) {
+	if model != e.model {
+		e.model = model
+		if m, ok := model.(console.Initializer); ok {
+			m.Init(e)
+		}
+	}
+	model.Update(e)
+	model.Draw(e.Console)
+	e.Console.Flush()
+	e.Input.EndFrame()
+}
+
+// Run calls Step the given number of times.
+func (e *Engine) Run(model console.Model, frames int) {
+	for i := 0; i < frames; i++ {
+		e.Step(model)
+	}
+}
+
+// Replay runs one Step per frame of an input replay, the model reading the
+// replayed input instead of Input. It returns the number of frames played.
+// Models using randomness have to be seeded with the seed of the recording
+// beforehand.
+func (e *Engine) Replay(model console.Model, replay *input.Replay) int {
+	e.replay = replay
+	defer func() {
+		e.replay = nil
+	}()
+	frames := 0
+	for replay.Next() {
+		e.Step(model)
+		frames++
+	}
+	return frames
+}
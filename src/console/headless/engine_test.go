@@ -0,0 +1,75 @@
+package headless_test
+
+import (
+	"testing"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/headless"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// cursorModel draws a '@' at the last clicked position on a dotted floor.
+type cursorModel struct {
+	inits int
+	pos   geometry.Point
+}
+
+func (m *cursorModel) Init(engine console.Engine) {
+	m.inits++
+}
+
+func (m *cursorModel) Update(engine console.Engine) {
+	in := engine.GetInput()
+	if in.IsMouseLeft() {
+		m.pos = in.GetMousePos()
+	}
+}
+
+func (m *cursorModel) Draw(con console.CellInterface) {
+	floor := common.Cell{Char: '.', Foreground: common.White, Background: common.Black}
+	con.Fill(geometry.NewRect(0, 0, con.Size().X, con.Size().Y), floor)
+	con.Set(m.pos, common.Cell{Char: '@', Foreground: common.White, Background: common.Black})
+}
+
+func TestEngineStep(t *testing.T) {
+	e := headless.NewEngine(console.GridConfig{GridWidth: 4, GridHeight: 2})
+	m := &cursorModel{}
+	e.Step(m)
+	if got, want := e.Renderer.String(), "@...\n....\n"; got != want {
+		t.Errorf("first frame = %q, want %q", got, want)
+	}
+	if frames := e.Renderer.Frames(); len(frames) != 1 || len(frames[0].Cells) != 8 {
+		t.Errorf("first frames = %+v, want one frame of 8 cells", frames)
+	}
+
+	e.Renderer.ClearFrames()
+	e.Input.ClickLeft(geometry.Point{X: 2, Y: 1})
+	e.Step(m)
+	if got, want := e.Renderer.String(), "....\n..@.\n"; got != want {
+		t.Errorf("frame after the click = %q, want %q", got, want)
+	}
+	frames := e.Renderer.Frames()
+	if len(frames) != 1 || frames[0].IsFullRedraw {
+		t.Fatalf("frames after the click = %+v, want one delta frame", frames)
+	}
+	changed := map[geometry.Point]rune{}
+	for _, cellAt := range frames[0].Cells {
+		changed[cellAt.P] = cellAt.Cell.Char
+	}
+	if len(changed) != 2 || changed[geometry.Point{}] != '.' || changed[geometry.Point{X: 2, Y: 1}] != '@' {
+		t.Errorf("changed cells = %v, want '.' at (0,0) and '@' at (2,1)", changed)
+	}
+
+	e.Renderer.ClearFrames()
+	e.Run(m, 3)
+	if frames := e.Renderer.Frames(); len(frames) != 0 {
+		t.Errorf("frames without changes = %+v, want none", frames)
+	}
+	if e.Input.IsMouseLeft() {
+		t.Error("the click is still reported after the frame")
+	}
+	if m.inits != 1 {
+		t.Errorf("Init called %d times, want 1", m.inits)
+	}
+}
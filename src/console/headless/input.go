@@ -0,0 +1,280 @@
+package headless
+
+import (
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/input"
+)
+
+// Input is a scriptable input.GridInput. The state set with its methods is
+// valid for one frame: EndFrame has to be called after the model's Update
+// (Engine.Step does it) to reset the just pressed keys and buttons, like the
+// real input does between two ebiten ticks.
+//
+// Key names are the ones of ebiten.Key.String(), for example "Enter",
+// "Escape", "ArrowUp" or "C".
+//
+// The methods also queue the matching events, see input.GridInput.GetEvents,
+// in the order they are called.
+type Input struct {
+	// Actions are the bindings of the menu actions, nil for the defaults of
+	// input.MenuActions.
+	Actions *input.ActionMap
+
+	mouseHalfPos geometry.Point
+	mousePos     geometry.Point
+	lastMousePos geometry.Point
+	mouseLeft    bool
+	mouseRight   bool
+	keys         []string
+	chars        []rune
+	shift        bool
+	control      bool
+	alt          bool
+	wheel        int
+	buttons      []string // gamepad buttons
+	direction    geometry.Point
+	events       input.EventQueue
+}
+
+func NewInput() *Input {
+	return &Input{}
+}
+
+// PressKeys marks the given keys as just pressed in the current frame.
+func (i *Input) PressKeys(keys ...string) {
+	i.keys = append(i.keys, keys...)
+	for _, key := range keys {
+		i.events.KeyDown(key)
+	}
+}
+
+// ReleaseKeys queues KeyUp events for the given keys. The polled state is not
+// changed, it only knows the keys just pressed.
+func (i *Input) ReleaseKeys(keys ...string) {
+	for _, key := range keys {
+		i.events.KeyUp(key)
+	}
+}
+
+// RepeatKeys queues KeyRepeat events for the given keys, as if they were held
+// past the key repeat delay.
+func (i *Input) RepeatKeys(keys ...string) {
+	for _, key := range keys {
+		i.events.KeyRepeat(key)
+	}
+}
+
+// TypeText adds the runes of s to the characters typed in the current frame.
+// It doesn't press the keys: text entry widgets get both, but most models
+// only need one of them.
+func (i *Input) TypeText(s string) {
+	for _, r := range s {
+		i.chars = append(i.chars, r)
+		i.events.Char(r)
+	}
+}
+
+// HoldShift holds or releases the Shift key, see input.ModifierInput. Unlike
+// the pressed keys, it stays held across frames.
+func (i *Input) HoldShift(held bool) {
+	i.shift = held
+	i.updateModifiers()
+}
+
+// HoldControl holds or releases the Control key, like HoldShift.
+func (i *Input) HoldControl(held bool) {
+	i.control = held
+	i.updateModifiers()
+}
+
+// HoldAlt holds or releases the Alt key, like HoldShift.
+func (i *Input) HoldAlt(held bool) {
+	i.alt = held
+	i.updateModifiers()
+}
+
+func (i *Input) updateModifiers() {
+	var mods input.Modifiers
+	if i.shift {
+		mods |= input.ModShift
+	}
+	if i.control {
+		mods |= input.ModControl
+	}
+	if i.alt {
+		mods |= input.ModAlt
+	}
+	i.events.SetModifiers(mods)
+}
+
+func (i *Input) IsShiftDown() bool {
+	return i.shift
+}
+
+func (i *Input) IsControlDown() bool {
+	return i.control
+}
+
+func (i *Input) IsAltDown() bool {
+	return i.alt
+}
+
+// TurnWheel turns the mouse wheel by the given notches in the current frame,
+// positive being up, see input.WheelInput.
+func (i *Input) TurnWheel(notches int) {
+	i.wheel += notches
+	i.events.Wheel(notches)
+}
+
+func (i *Input) GetWheel() int {
+	return i.wheel
+}
+
+// ConnectGamepad queues the connection event of a gamepad.
+func (i *Input) ConnectGamepad(id int) {
+	i.events.GamepadConnected(id)
+}
+
+// DisconnectGamepad queues the disconnection event of a gamepad.
+func (i *Input) DisconnectGamepad(id int) {
+	i.events.GamepadDisconnected(id)
+}
+
+// PressGamepadButtons marks the given buttons of the gamepad 0 as just
+// pressed in the current frame, see the input.Gamepad constants.
+func (i *Input) PressGamepadButtons(buttons ...string) {
+	i.buttons = append(i.buttons, buttons...)
+	for _, button := range buttons {
+		i.events.GamepadDown(0, button)
+	}
+}
+
+// PushGamepad sets the gamepad direction of the current frame, as reported
+// after the repeat, see input.GridInput.GetGamepadDirection.
+func (i *Input) PushGamepad(dir geometry.Point) {
+	i.direction = dir
+}
+
+// MoveMouse moves the mouse to the given square cell position, on its left
+// half-width cell.
+func (i *Input) MoveMouse(p geometry.Point) {
+	i.mousePos = p
+	i.mouseHalfPos = console.SquareToHalfWidth(p)
+	i.events.MouseMove(i.mousePos, i.mouseHalfPos)
+}
+
+// MoveMouseHalfWidth moves the mouse to the given half-width cell position.
+func (i *Input) MoveMouseHalfWidth(p geometry.Point) {
+	i.mouseHalfPos = p
+	i.mousePos = console.HalfWidthToSquare(p)
+	i.events.MouseMove(i.mousePos, i.mouseHalfPos)
+}
+
+// ClickLeft moves the mouse to the given square cell position and clicks the
+// left button.
+func (i *Input) ClickLeft(p geometry.Point) {
+	i.MoveMouse(p)
+	i.PressButton(input.ButtonLeft)
+	i.ReleaseButton(input.ButtonLeft)
+}
+
+// ClickRight moves the mouse to the given square cell position and clicks
+// the right button.
+func (i *Input) ClickRight(p geometry.Point) {
+	i.MoveMouse(p)
+	i.PressButton(input.ButtonRight)
+	i.ReleaseButton(input.ButtonRight)
+}
+
+// PressButton presses a mouse button at the mouse position. It stays held
+// until ReleaseButton, so that moving the mouse in between drags it.
+func (i *Input) PressButton(b input.MouseButton) {
+	switch b {
+	case input.ButtonLeft:
+		i.mouseLeft = true
+	case input.ButtonRight:
+		i.mouseRight = true
+	}
+	i.events.MouseDown(b)
+}
+
+// ReleaseButton releases a mouse button pressed by PressButton.
+func (i *Input) ReleaseButton(b input.MouseButton) {
+	i.events.MouseUp(b)
+}
+
+// EndFrame resets the per frame state.
+func (i *Input) EndFrame() {
+	i.lastMousePos = i.mousePos
+	i.mouseLeft = false
+	i.mouseRight = false
+	i.keys = i.keys[:0]
+	i.chars = i.chars[:0]
+	i.wheel = 0
+	i.buttons = i.buttons[:0]
+	i.direction = geometry.Point{}
+	i.events.Clear()
+}
+
+func (i *Input) GetMousePos() geometry.Point {
+	return i.mousePos
+}
+
+func (i *Input) GetHalfWidthMousePos() geometry.Point {
+	return i.mouseHalfPos
+}
+
+func (i *Input) HasMouseMoved() bool {
+	return i.mousePos != i.lastMousePos
+}
+
+func (i *Input) IsMouseLeft() bool {
+	return i.mouseLeft
+}
+
+func (i *Input) IsMouseRight() bool {
+	return i.mouseRight
+}
+
+func (i *Input) IsMenuClose() bool {
+	return input.IsMenuTriggered(i, i.Actions, input.MenuClose)
+}
+
+func (i *Input) IsMenuConfirm() bool {
+	return input.IsMenuTriggered(i, i.Actions, input.MenuConfirm)
+}
+
+func (i *Input) IsMenuDown() bool {
+	return input.IsMenuTriggered(i, i.Actions, input.MenuDown) || i.direction.Y > 0
+}
+
+func (i *Input) IsMenuUp() bool {
+	return input.IsMenuTriggered(i, i.Actions, input.MenuUp) || i.direction.Y < 0
+}
+
+func (i *Input) GetJustPressedKeys() []string {
+	keys := make([]string, len(i.keys))
+	copy(keys, i.keys)
+	return keys
+}
+
+func (i *Input) GetInputChars() []rune {
+	chars := make([]rune, len(i.chars))
+	copy(chars, i.chars)
+	return chars
+}
+
+func (i *Input) GetEvents() []input.Event {
+	return i.events.Events()
+}
+
+func (i *Input) GetJustPressedGamepadButtons() []string {
+	buttons := make([]string, len(i.buttons))
+	copy(buttons, i.buttons)
+	return buttons
+}
+
+func (i *Input) GetGamepadDirection() geometry.Point {
+	return i.direction
+}
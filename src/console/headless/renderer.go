@@ -0,0 +1,67 @@
+// Package headless provides a console backend that keeps everything in
+// memory, so that models can be driven by tests or servers without a display.
+package headless
+
+import (
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// Renderer is a console.Renderer that applies the frames to an in-memory
+// screen and keeps the stream of rendered frames.
+type Renderer struct {
+	screen *console.Screen
+	frames []console.Frame
+}
+
+func NewRenderer(config console.GridConfig) *Renderer {
+	return &Renderer{
+		screen: console.NewScreen(geometry.Point{X: config.GridWidth, Y: config.GridHeight}),
+	}
+}
+
+// Render implements console.Renderer.
+func (r *Renderer) Render(frame console.Frame) {
+	cells := make([]console.FrameCell, len(frame.Cells))
+	copy(cells, frame.Cells)
+	frame.Cells = cells
+	r.frames = append(r.frames, frame)
+	r.screen.Apply(frame, nil)
+}
+
+// Resize implements console.Resizer.
+func (r *Renderer) Resize(size geometry.Point) {
+	r.screen.Resize(size)
+}
+
+// Grid returns the square grid as it would currently be displayed.
+func (r *Renderer) Grid() geometry.Grid {
+	return r.screen.Square
+}
+
+// HalfWidthGrid returns the half-width grid as it would currently be
+// displayed above the square grid.
+func (r *Renderer) HalfWidthGrid() geometry.Grid {
+	return r.screen.HalfWidth
+}
+
+// Screen returns both displayed grids.
+func (r *Renderer) Screen() *console.Screen {
+	return r.screen
+}
+
+// Frames returns the frames rendered since the creation of the renderer or
+// the last call to ClearFrames.
+func (r *Renderer) Frames() []console.Frame {
+	return r.frames
+}
+
+// ClearFrames forgets the frames rendered so far. The grid is kept.
+func (r *Renderer) ClearFrames() {
+	r.frames = nil
+}
+
+// String returns the runes of the displayed square grid, one line per row.
+func (r *Renderer) String() string {
+	return r.screen.Square.String()
+}
@@ -0,0 +1,193 @@
+package console
+
+import (
+	"image/color"
+	"sort"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// Conventional layer names and z values. The console's own grid, the one
+// written by Console.Set, is always below all the layers.
+const (
+	LayerMap      = "map"
+	LayerEntities = "entities"
+	LayerEffects  = "effects"
+	LayerUI       = "ui"
+	LayerCursor   = "cursor"
+
+	ZMap      = 0
+	ZEntities = 10
+	ZEffects  = 20
+	ZUI       = 30
+	ZCursor   = 40
+)
+
+// TransparentCell is the content of an empty layer cell: it shows the layers
+// below unchanged.
+var TransparentCell = common.Cell{}
+
+// Layer is a named grid composited above the console's grid and the layers
+// with a lower z value when the console is flushed. For each layer cell:
+//
+//   - a nil Background keeps the background below; a translucent one is
+//     alpha blended over it.
+//   - a zero Char is a transparent glyph: the glyph below stays visible, and a
+//     non-nil Foreground only tints it (blended over its color).
+//   - any other Char replaces the glyph below; a translucent Foreground is
+//     blended over the resulting background.
+//
+// Layer implements CellInterface, so it can be given to code drawing into a
+// console. Its Flush does nothing: layers are composited by Console.Flush.
+type Layer struct {
+	name   string
+	z      int
+	hidden bool
+	grid   geometry.Grid
+}
+
+func (l *Layer) Name() string {
+	return l.name
+}
+
+func (l *Layer) Z() int {
+	return l.z
+}
+
+// SetHidden hides or shows the layer. A hidden layer keeps its content but is
+// not composited.
+func (l *Layer) SetHidden(hidden bool) {
+	l.hidden = hidden
+}
+
+func (l *Layer) Hidden() bool {
+	return l.hidden
+}
+
+func (l *Layer) Set(p geometry.Point, cell common.Cell) {
+	l.grid.Set(p, cell)
+}
+
+func (l *Layer) At(p geometry.Point) common.Cell {
+	return l.grid.At(p)
+}
+
+func (l *Layer) Size() geometry.Point {
+	return l.grid.Size()
+}
+
+func (l *Layer) Fill(rect geometry.Rect, cell common.Cell) {
+	l.grid.Slice(rect).Fill(cell)
+}
+
+// Clear makes the whole layer transparent.
+func (l *Layer) Clear() {
+	l.grid.Fill(TransparentCell)
+}
+
+// ClearScreen is the same as Clear.
+func (l *Layer) ClearScreen() {
+	l.Clear()
+}
+
+func (l *Layer) Flush() {}
+
+// AddLayer returns the layer with the given name, creating it with the given z
+// value if necessary. Layers with the same z value are composited in creation
+// order.
+func (c *Console) AddLayer(name string, z int) *Layer {
+	if l := c.Layer(name); l != nil {
+		return l
+	}
+	size := c.drawGrid.Size()
+	l := &Layer{name: name, z: z, grid: geometry.NewGrid(size.X, size.Y)}
+	l.Clear()
+	c.layers = append(c.layers, l)
+	sort.SliceStable(c.layers, func(i, j int) bool {
+		return c.layers[i].z < c.layers[j].z
+	})
+	return l
+}
+
+// AddDefaultLayers creates the conventional layers: map, entities, effects,
+// ui and cursor.
+func (c *Console) AddDefaultLayers() {
+	c.AddLayer(LayerMap, ZMap)
+	c.AddLayer(LayerEntities, ZEntities)
+	c.AddLayer(LayerEffects, ZEffects)
+	c.AddLayer(LayerUI, ZUI)
+	c.AddLayer(LayerCursor, ZCursor)
+}
+
+// Layer returns the layer with the given name, or nil.
+func (c *Console) Layer(name string) *Layer {
+	for _, l := range c.layers {
+		if l.name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// RemoveLayer removes the layer with the given name, if any.
+func (c *Console) RemoveLayer(name string) {
+	for i, l := range c.layers {
+		if l.name == name {
+			c.layers = append(c.layers[:i], c.layers[i+1:]...)
+			return
+		}
+	}
+}
+
+// composite returns the grid to be displayed: the console's grid if there are
+// no layers, otherwise the composition of the console's grid and the visible
+// layers.
+func (c *Console) composite() geometry.Grid {
+	if len(c.layers) == 0 {
+		return c.drawGrid
+	}
+	size := c.drawGrid.Size()
+	if c.composeGrid.Ug == nil || c.composeGrid.Size() != size {
+		c.composeGrid = geometry.NewGrid(size.X, size.Y)
+	}
+	c.composeGrid.Copy(c.drawGrid)
+	for _, l := range c.layers {
+		if l.hidden {
+			continue
+		}
+		l.grid.Iter(func(p geometry.Point, above common.Cell) {
+			if above == TransparentCell {
+				return
+			}
+			c.composeGrid.Set(p, ComposeCell(c.composeGrid.At(p), above))
+		})
+	}
+	return c.composeGrid
+}
+
+// ComposeCell returns the cell resulting from drawing a layer cell above
+// another, following the rules described in the Layer documentation.
+func ComposeCell(below, above common.Cell) common.Cell {
+	if above.Background != nil {
+		below.Background = blendOver(above.Background, below.Background)
+	}
+	if above.Char == 0 {
+		if above.Foreground != nil {
+			below.Foreground = blendOver(above.Foreground, below.Foreground)
+		}
+		return below
+	}
+	below.Char = above.Char
+	if above.Foreground != nil {
+		below.Foreground = blendOver(above.Foreground, below.Background)
+	}
+	return below
+}
+
+func blendOver(above, below color.Color) color.Color {
+	if below == nil {
+		return above
+	}
+	return common.AlphaBlend(above, below)
+}
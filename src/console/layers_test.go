@@ -0,0 +1,106 @@
+package console
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// sameColor reports whether two colors, possibly nil, have the same RGBA
+// values.
+func sameColor(a, b color.Color) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
+
+func TestComposeCell(t *testing.T) {
+	// the standard library colors: the common colors are tone mapped
+	halfRed := color.NRGBA{R: 0xff, A: 0x80}
+	below := common.Cell{Char: 'x', Foreground: color.White, Background: color.Black}
+	tests := []struct {
+		name  string
+		below common.Cell
+		above common.Cell
+		want  common.Cell
+	}{
+		{"transparent", below, TransparentCell, below},
+		{"opaque background", below, common.Cell{Background: common.Red},
+			common.Cell{Char: 'x', Foreground: color.White, Background: common.Red}},
+		{"translucent background", below, common.Cell{Background: halfRed},
+			common.Cell{Char: 'x', Foreground: color.White, Background: color.RGBA64{R: 0x8080, A: 0xffff}}},
+		{"background over none", common.Cell{Char: 'x'}, common.Cell{Background: halfRed},
+			common.Cell{Char: 'x', Background: halfRed}},
+		{"tint", below, common.Cell{Foreground: halfRed},
+			common.Cell{Char: 'x', Foreground: color.RGBA64{R: 0xffff, G: 0x7f7f, B: 0x7f7f, A: 0xffff}, Background: color.Black}},
+		{"glyph", below, common.Cell{Char: '@'},
+			common.Cell{Char: '@', Foreground: color.White, Background: color.Black}},
+		{"translucent glyph", below, common.Cell{Char: '@', Foreground: halfRed},
+			common.Cell{Char: '@', Foreground: color.RGBA64{R: 0x8080, A: 0xffff}, Background: color.Black}},
+		{"glyph and background", below, common.Cell{Char: '@', Foreground: halfRed, Background: color.White},
+			common.Cell{Char: '@', Foreground: color.RGBA64{R: 0xffff, G: 0x7f7f, B: 0x7f7f, A: 0xffff}, Background: color.White}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ComposeCell(tt.below, tt.above)
+			if got.Char != tt.want.Char || !sameColor(got.Foreground, tt.want.Foreground) || !sameColor(got.Background, tt.want.Background) {
+				t.Errorf("ComposeCell() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayers(t *testing.T) {
+	size := geometry.Point{X: 2, Y: 1}
+	r := &screenRenderer{screen: NewScreen(size)}
+	con := NewConsole(GridConfig{GridWidth: size.X, GridHeight: size.Y}, r)
+	con.ClearScreen()
+	con.Set(geometry.Point{}, common.Cell{Char: 'x', Foreground: common.White, Background: common.Black})
+	b := con.AddLayer("b", 20)
+	a := con.AddLayer("a", 10)
+	c := con.AddLayer("c", 10)
+	if l := con.AddLayer("a", 30); l != a || l.Z() != 10 {
+		t.Errorf("AddLayer of an existing name = %v, want the existing layer", l)
+	}
+	a.Set(geometry.Point{}, common.Cell{Char: 'a'})
+	b.Set(geometry.Point{}, common.Cell{Char: 'b'})
+	c.Set(geometry.Point{}, common.Cell{Char: 'c'})
+	a.Set(geometry.Point{X: 1}, common.Cell{Background: common.Red})
+
+	tests := []struct {
+		name string
+		act  func()
+		want rune
+	}{
+		{"highest z", func() {}, 'b'},
+		{"creation order for the same z", func() { b.SetHidden(true) }, 'c'},
+		{"lowest z", func() { c.SetHidden(true) }, 'a'},
+		{"console grid", func() { a.SetHidden(true) }, 'x'},
+		{"shown again", func() { c.SetHidden(false) }, 'c'},
+		{"removed", func() { con.RemoveLayer("c") }, 'x'},
+	}
+	for _, tt := range tests {
+		tt.act()
+		con.Flush()
+		if got := r.screen.Square.At(geometry.Point{}).Char; got != tt.want {
+			t.Errorf("%s: displayed rune = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if con.Layer("c") != nil {
+		t.Error("Layer returns a removed layer")
+	}
+	if got := con.At(geometry.Point{}).Char; got != 'x' {
+		t.Errorf("console cell = %q, want 'x': layers must not change it", got)
+	}
+
+	a.SetHidden(false)
+	con.Flush()
+	if got := r.screen.Square.At(geometry.Point{X: 1}); got.Char != ' ' || !sameColor(got.Background, common.Red) {
+		t.Errorf("cell under a background only layer cell = %+v, want a blank with a red background", got)
+	}
+}
@@ -0,0 +1,205 @@
+// Package offscreen paints console cells onto standard library images,
+// without ebiten and without a display.
+package offscreen
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io/fs"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/console/tileset"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// LoadFont parses a TrueType or OpenType font file from a file system, for
+// example the embedded one of the main package.
+func LoadFont(fsys fs.FS, path string) (*sfnt.Font, error) {
+	data, err := fs.ReadFile(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.Parse(data)
+}
+
+// Painter draws cells as tiles of a fixed size: the background fills the
+// tile, and the glyph is drawn at the top left of the tile with a font size
+// equal to the tile height, like the ebiten renderer does.
+type Painter struct {
+	fonts      []painterFont // fallback chain
+	buf        sfnt.Buffer
+	tileWidth  int
+	tileHeight int
+	tileset    *tileset.Tileset
+}
+
+type painterFont struct {
+	font   *sfnt.Font
+	face   font.Face
+	ascent fixed.Int26_6
+}
+
+// NewPainter returns a painter drawing the glyphs with a single font.
+func NewPainter(f *sfnt.Font, tileWidth, tileHeight int) (*Painter, error) {
+	return NewFallbackPainter([]*sfnt.Font{f}, tileWidth, tileHeight)
+}
+
+// NewFallbackPainter returns a painter drawing each rune with the first font
+// of the list that has a glyph for it, like the fallback chain of the ebiten
+// renderer. Passing the fonts of the renderer makes the screenshots look like
+// the window.
+func NewFallbackPainter(fonts []*sfnt.Font, tileWidth, tileHeight int) (*Painter, error) {
+	p := &Painter{
+		tileWidth:  tileWidth,
+		tileHeight: tileHeight,
+	}
+	for _, f := range fonts {
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{
+			Size:    float64(tileHeight),
+			DPI:     72,
+			Hinting: font.HintingFull,
+		})
+		if err != nil {
+			return nil, err
+		}
+		p.fonts = append(p.fonts, painterFont{font: f, face: face, ascent: face.Metrics().Ascent})
+	}
+	return p, nil
+}
+
+// SetTileset makes the painter draw the runes with the tiles of an atlas, like
+// the TilesetRendering mode of the ebiten renderer. Runes without a tile are
+// drawn with the font. A nil tileset goes back to the font only.
+func (p *Painter) SetTileset(ts *tileset.Tileset) {
+	p.tileset = ts
+}
+
+// TileSize returns the size in pixels of a cell.
+func (p *Painter) TileSize() image.Point {
+	return image.Point{X: p.tileWidth, Y: p.tileHeight}
+}
+
+// Bounds returns the pixel bounds of a grid of the given size in cells.
+func (p *Painter) Bounds(size geometry.Point) image.Rectangle {
+	return image.Rect(0, 0, size.X*p.tileWidth, size.Y*p.tileHeight)
+}
+
+// CellBounds returns the pixel bounds of the cell at the given position.
+func (p *Painter) CellBounds(pos geometry.Point) image.Rectangle {
+	min := image.Point{X: pos.X * p.tileWidth, Y: pos.Y * p.tileHeight}
+	return image.Rectangle{Min: min, Max: min.Add(p.TileSize())}
+}
+
+// HasGlyph reports whether one of the painter's fonts has a glyph for the
+// rune.
+func (p *Painter) HasGlyph(r rune) bool {
+	return p.fontFor(r) != nil
+}
+
+// fontFor returns the first font with a glyph for the rune, or nil.
+func (p *Painter) fontFor(r rune) *painterFont {
+	for i := range p.fonts {
+		index, err := p.fonts[i].font.GlyphIndex(&p.buf, r)
+		if err == nil && index != 0 {
+			return &p.fonts[i]
+		}
+	}
+	return nil
+}
+
+// DrawCell paints a cell at the given grid position. Runes missing from the
+// fonts are drawn as a space.
+func (p *Painter) DrawCell(dst draw.Image, pos geometry.Point, cell common.Cell) {
+	p.drawTile(dst, p.CellBounds(pos), cell)
+}
+
+// DrawHalves paints two half-width cells in place of the square cell at the
+// given grid position.
+func (p *Painter) DrawHalves(dst draw.Image, pos geometry.Point, left, right common.Cell) {
+	bounds := p.CellBounds(pos)
+	mid := bounds.Min.X + p.tileWidth/2
+	p.drawTile(dst, image.Rect(bounds.Min.X, bounds.Min.Y, mid, bounds.Max.Y), left)
+	p.drawTile(dst, image.Rect(mid, bounds.Min.Y, bounds.Max.X, bounds.Max.Y), right)
+}
+
+func (p *Painter) drawTile(dst draw.Image, bounds image.Rectangle, cell common.Cell) {
+	draw.Draw(dst, bounds, image.NewUniform(colorOr(cell.Background, common.Black)), image.Point{}, draw.Src)
+	if p.tileset != nil {
+		if index, ok := p.tileset.Tile(cell.Char); ok {
+			p.drawAtlasTile(dst, bounds, index, colorOr(cell.Foreground, common.White))
+			return
+		}
+	}
+	if cell.Char == ' ' {
+		return
+	}
+	f := p.fontFor(cell.Char)
+	if f == nil {
+		return
+	}
+	drawer := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(colorOr(cell.Foreground, common.White)),
+		Face: f.face,
+		Dot:  fixed.Point26_6{X: fixed.I(bounds.Min.X), Y: fixed.I(bounds.Min.Y) + f.ascent},
+	}
+	drawer.DrawString(string(cell.Char))
+}
+
+// drawAtlasTile draws a tile of the tileset scaled to bounds with the nearest
+// pixel, multiplying its colors by fg, as ebiten's color scale does.
+func (p *Painter) drawAtlasTile(dst draw.Image, bounds image.Rectangle, index int, fg color.Color) {
+	src := p.tileset.TileBounds(index)
+	cr, cg, cb, ca := fg.RGBA()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		sy := src.Min.Y + (y-bounds.Min.Y)*src.Dy()/bounds.Dy()
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			sx := src.Min.X + (x-bounds.Min.X)*src.Dx()/bounds.Dx()
+			r, g, b, a := p.tileset.Image.At(sx, sy).RGBA()
+			if a == 0 {
+				continue
+			}
+			tinted := color.RGBA64{
+				R: uint16(r * cr / 0xffff),
+				G: uint16(g * cg / 0xffff),
+				B: uint16(b * cb / 0xffff),
+				A: uint16(a * ca / 0xffff),
+			}
+			dr, dg, db, _ := dst.At(x, y).RGBA()
+			inv := 0xffff - uint32(tinted.A)
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(uint32(tinted.R) + dr*inv/0xffff),
+				G: uint16(uint32(tinted.G) + dg*inv/0xffff),
+				B: uint16(uint32(tinted.B) + db*inv/0xffff),
+				A: 0xffff,
+			})
+		}
+	}
+}
+
+// DrawGrid paints all the cells of a grid.
+func (p *Painter) DrawGrid(dst draw.Image, gd geometry.Grid) {
+	gd.Iter(func(pos geometry.Point, cell common.Cell) {
+		p.DrawCell(dst, pos, cell)
+	})
+}
+
+// Image returns a new image with the whole grid painted on it.
+func (p *Painter) Image(gd geometry.Grid) *image.RGBA {
+	img := image.NewRGBA(p.Bounds(gd.Size()))
+	p.DrawGrid(img, gd)
+	return img
+}
+
+func colorOr(c color.Color, def color.Color) color.Color {
+	if c == nil {
+		return def
+	}
+	return c
+}
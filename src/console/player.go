@@ -0,0 +1,135 @@
+package console
+
+import (
+	"time"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// Player replays a Recording. It implements Model, so it can take the place
+// of the game model in the game loop: Update advances the playback with the
+// wall clock, and Draw copies the replayed grids into the console.
+type Player struct {
+	rec        *Recording
+	screen     *Screen
+	next       int           // index of the next frame to apply
+	elapsed    time.Duration // playback position
+	speed      float64
+	paused     bool
+	lastUpdate time.Time
+}
+
+func NewPlayer(rec *Recording) *Player {
+	return &Player{
+		rec:    rec,
+		screen: NewScreen(geometry.Point{X: rec.Width, Y: rec.Height}),
+		speed:  1,
+	}
+}
+
+// SetSpeed sets the playback speed relative to the original speed.
+func (p *Player) SetSpeed(speed float64) {
+	p.speed = speed
+}
+
+// SetPaused pauses or resumes the playback.
+func (p *Player) SetPaused(paused bool) {
+	p.paused = paused
+}
+
+// FrameCount returns the number of frames in the recording.
+func (p *Player) FrameCount() int {
+	return len(p.rec.Frames)
+}
+
+// Position returns the number of frames applied so far.
+func (p *Player) Position() int {
+	return p.next
+}
+
+// Done reports whether all the frames have been applied.
+func (p *Player) Done() bool {
+	return p.next >= len(p.rec.Frames)
+}
+
+// Grid returns the square grid as of the current playback position.
+func (p *Player) Grid() geometry.Grid {
+	return p.screen.Square
+}
+
+// Screen returns the square and half-width grids as of the current playback
+// position.
+func (p *Player) Screen() *Screen {
+	return p.screen
+}
+
+// Advance moves the playback position forward by dt, scaled by the speed,
+// and applies the frames recorded up to the new position.
+func (p *Player) Advance(dt time.Duration) {
+	if p.paused {
+		return
+	}
+	p.elapsed += time.Duration(float64(dt) * p.speed)
+	for p.next < len(p.rec.Frames) && p.rec.Frames[p.next].Time <= p.elapsed {
+		p.apply(p.rec.Frames[p.next].Frame)
+		p.next++
+	}
+}
+
+// Seek moves the playback position so that the frames up to index (excluded)
+// are applied. It starts from the closest keyframe before index.
+func (p *Player) Seek(index int) {
+	if index < 0 {
+		index = 0
+	}
+	if index > len(p.rec.Frames) {
+		index = len(p.rec.Frames)
+	}
+	start := 0
+	for i := index - 1; i >= 0; i-- {
+		if frame := p.rec.Frames[i].Frame; frame.IsFullRedraw && !frame.IsHalfWidth {
+			start = i
+			break
+		}
+	}
+	p.screen.Clear()
+	for i := start; i < index; i++ {
+		p.apply(p.rec.Frames[i].Frame)
+	}
+	p.next = index
+	if index > 0 {
+		p.elapsed = p.rec.Frames[index-1].Time
+	} else {
+		p.elapsed = 0
+	}
+}
+
+func (p *Player) apply(frame Frame) {
+	p.screen.Apply(frame, nil)
+}
+
+// Update implements Model.
+func (p *Player) Update(engine Engine) {
+	now := time.Now()
+	var dt time.Duration
+	if !p.lastUpdate.IsZero() {
+		dt = now.Sub(p.lastUpdate)
+	}
+	p.lastUpdate = now
+	p.Advance(dt)
+}
+
+// Draw implements Model. The half-width grid is only drawn if con implements
+// HalfWidthInterface.
+func (p *Player) Draw(con CellInterface) {
+	p.screen.Square.Iter(func(pos geometry.Point, cell common.Cell) {
+		con.Set(pos, cell)
+	})
+	if halfCon, ok := con.(HalfWidthInterface); ok {
+		half := halfCon.HalfWidth()
+		p.screen.HalfWidth.Iter(func(pos geometry.Point, cell common.Cell) {
+			half.Set(pos, cell)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package console
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/memmaker/ECon/geometry"
+)
+
+// DefaultKeyframeInterval is the number of frames between two keyframes in
+// the recordings made by Console.StartRecording.
+const DefaultKeyframeInterval = 100
+
+// RecordingHeader is the first value of a recording file.
+type RecordingHeader struct {
+	Width            int
+	Height           int
+	KeyframeInterval int
+}
+
+// RecordedFrame is a frame of a recording, stamped with the time elapsed
+// since the start of the recording. Keyframes have Frame.IsFullRedraw set
+// and contain every cell of the square grid. When the half-width grid is used,
+// they are followed by a full redraw frame of the half-width grid.
+type RecordedFrame struct {
+	Time  time.Duration
+	Frame Frame
+}
+
+// Recording is a decoded recording file.
+type Recording struct {
+	RecordingHeader
+	Frames []RecordedFrame
+}
+
+// Recorder writes frames to a gzip compressed gob stream. Every
+// KeyframeInterval frames it writes a keyframe instead of the delta, so that
+// players can seek without replaying the whole recording.
+type Recorder struct {
+	header RecordingHeader
+	screen *Screen
+	zw     *gzip.Writer
+	enc    *gob.Encoder
+	start  time.Time
+	count  int
+}
+
+// NewRecorder returns a recorder writing to w frames for a grid of the given
+// size. A keyframeInterval <= 0 means DefaultKeyframeInterval.
+func NewRecorder(w io.Writer, size geometry.Point, keyframeInterval int) (*Recorder, error) {
+	if keyframeInterval <= 0 {
+		keyframeInterval = DefaultKeyframeInterval
+	}
+	zw := gzip.NewWriter(w)
+	rec := &Recorder{
+		header: RecordingHeader{Width: size.X, Height: size.Y, KeyframeInterval: keyframeInterval},
+		screen: NewScreen(size),
+		zw:     zw,
+		enc:    gob.NewEncoder(zw),
+		start:  time.Now(),
+	}
+	if err := rec.enc.Encode(&rec.header); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Record writes a frame, stamped with the time elapsed since the creation of
+// the recorder.
+func (r *Recorder) Record(frame Frame) error {
+	return r.RecordAt(time.Since(r.start), frame)
+}
+
+// RecordAt writes a frame with an explicit time stamp.
+func (r *Recorder) RecordAt(t time.Duration, frame Frame) error {
+	r.screen.Apply(frame, nil)
+	isKeyframe := r.count%r.header.KeyframeInterval == 0
+	r.count++
+	if !isKeyframe || frame.IsFullRedraw && !frame.IsHalfWidth {
+		return r.enc.Encode(&RecordedFrame{Time: t, Frame: frame})
+	}
+	for _, full := range r.screen.Frames() {
+		if err := r.enc.Encode(&RecordedFrame{Time: t, Frame: full}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes the compressed stream. It does not close the underlying
+// writer.
+func (r *Recorder) Close() error {
+	return r.zw.Close()
+}
+
+// ReadRecording decodes a whole recording written by a Recorder.
+func ReadRecording(rd io.Reader) (*Recording, error) {
+	zr, err := gzip.NewReader(rd)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	dec := gob.NewDecoder(zr)
+	rec := &Recording{}
+	if err := dec.Decode(&rec.RecordingHeader); err != nil {
+		return nil, err
+	}
+	for {
+		var frame RecordedFrame
+		err := dec.Decode(&frame)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec.Frames = append(rec.Frames, frame)
+	}
+	return rec, nil
+}
@@ -0,0 +1,91 @@
+package console
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// recordingRenderer records every rendered frame, stamped with the number of
+// the flush in milliseconds.
+type recordingRenderer struct {
+	rec   *Recorder
+	flush int
+	err   error
+}
+
+func (r *recordingRenderer) Render(frame Frame) {
+	if r.err == nil {
+		r.err = r.rec.RecordAt(time.Duration(r.flush)*time.Millisecond, frame)
+	}
+}
+
+func TestRecordingRoundTrip(t *testing.T) {
+	const flushes = 11
+	size := geometry.Point{X: 6, Y: 4}
+	var buf bytes.Buffer
+	rec, err := NewRecorder(&buf, size, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer := &recordingRenderer{rec: rec}
+	con := NewConsole(GridConfig{GridWidth: size.X, GridHeight: size.Y}, renderer)
+	con.ClearScreen()
+	var expected []geometry.Grid
+	for i := 0; i < flushes; i++ {
+		renderer.flush = i
+		cell := common.Cell{Char: 'a' + rune(i), Foreground: common.RGBColor{R: 0.5, G: float64(i) / flushes, B: 1}, Background: common.Black}
+		if i%2 == 1 {
+			cell.Background = common.HSVColor{H: float64(i) / flushes, S: 0.5, V: 0.25}
+		}
+		con.Set(geometry.Point{X: i % size.X, Y: i / size.X}, cell)
+		con.Flush()
+		gd := geometry.NewGrid(size.X, size.Y)
+		gd.Copy(con.Grid())
+		expected = append(expected, gd)
+	}
+	if renderer.err != nil {
+		t.Fatal(renderer.err)
+	}
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	recording, err := ReadRecording(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recording.Width != size.X || recording.Height != size.Y || recording.KeyframeInterval != 4 {
+		t.Fatalf("header = %+v", recording.RecordingHeader)
+	}
+	// ends[i] is the number of frames recorded up to the end of the i-th
+	// flush.
+	var ends []int
+	for i, frame := range recording.Frames {
+		if i+1 == len(recording.Frames) || recording.Frames[i+1].Time != frame.Time {
+			ends = append(ends, i+1)
+		}
+	}
+	if len(ends) != flushes {
+		t.Fatalf("recording has frames for %d flushes, want %d", len(ends), flushes)
+	}
+	player := NewPlayer(recording)
+	check := func(flush int) {
+		t.Helper()
+		player.Seek(ends[flush])
+		expected[flush].Iter(func(p geometry.Point, want common.Cell) {
+			if got := player.Grid().At(p); got != want {
+				t.Errorf("flush %d: cell %v = %#v, want %#v", flush, p, got, want)
+			}
+		})
+	}
+	for i := flushes - 1; i >= 0; i-- {
+		check(i)
+	}
+	for i := 0; i < flushes; i++ {
+		check(i)
+	}
+}
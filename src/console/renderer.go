@@ -0,0 +1,11 @@
+package console
+
+// Renderer is the interface implemented by the backends that display the
+// frames computed by a Console, like the ebiten renderer or the headless one
+// used in tests.
+type Renderer interface {
+	// Render is called by Console.Flush for every non-empty frame. The
+	// frame's cells are reused by the console afterwards, so a renderer
+	// that wants to keep them has to copy them.
+	Render(frame Frame)
+}
@@ -0,0 +1,78 @@
+package console
+
+import (
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// Resizer is implemented by the renderers keeping a screen of the size of the
+// console, so that Console.Resize can resize them too.
+type Resizer interface {
+	Resize(size geometry.Point)
+}
+
+// ResizeListener is implemented by the models that adapt to the size of the
+// console, like game.Model. The engines that resize the console, for example
+// the ebiten Game in resizable mode, call OnResize before the next Update.
+type ResizeListener interface {
+	OnResize(size geometry.Point)
+}
+
+// Resize changes the number of columns and rows of the console, its
+// half-width grid and its layers. The content is kept where it fits and new
+// cells are blank, or transparent for the half-width grid and the layers. The
+// renderer is resized too if it implements Resizer, and the next Flush is a
+// full redraw.
+//
+// A recording keeps the size it was started with: players drop the cells
+// beyond it.
+func (c *Console) Resize(size geometry.Point) {
+	if size.X < 1 {
+		size.X = 1
+	}
+	if size.Y < 1 {
+		size.Y = 1
+	}
+	if size == c.drawGrid.Size() {
+		return
+	}
+	c.drawGrid = resize(c.drawGrid, size, c.delta.blank)
+	if c.halfGrid.Ug != nil {
+		c.halfGrid = resize(c.halfGrid, SquareToHalfWidth(size), TransparentCell)
+	}
+	for _, l := range c.layers {
+		l.grid = resize(l.grid, size, TransparentCell)
+	}
+	// the delta grids follow the size of the underlying grids in the next
+	// computeFrame, which is a full redraw
+	c.clearBeforeNextFlush = true
+	if r, ok := c.renderer.(Resizer); ok {
+		r.Resize(size)
+	}
+}
+
+// resize resizes gd with Grid.Resize, which keeps the underlying grid when it
+// shrinks, and sets the cells beyond the previous size to blank, as they may
+// hold the content from before a shrink.
+func resize(gd geometry.Grid, size geometry.Point, blank common.Cell) geometry.Grid {
+	old := gd.Size()
+	gd = gd.Resize(size.X, size.Y)
+	if size.X > old.X {
+		gd.Slice(geometry.NewRect(old.X, 0, size.X, size.Y)).Fill(blank)
+	}
+	if size.Y > old.Y {
+		gd.Slice(geometry.NewRect(0, old.Y, size.X, size.Y)).Fill(blank)
+	}
+	return gd
+}
+
+// Resize changes the size of the screen in square cells, keeping the content
+// where it fits. New square cells are blanks and new half-width cells are
+// transparent.
+func (s *Screen) Resize(size geometry.Point) {
+	if size == s.Size() {
+		return
+	}
+	s.Square = resize(s.Square, size, common.Cell{Char: ' ', Foreground: common.White, Background: common.Black})
+	s.HalfWidth = resize(s.HalfWidth, SquareToHalfWidth(size), TransparentCell)
+}
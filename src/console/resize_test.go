@@ -0,0 +1,120 @@
+package console
+
+import (
+	"testing"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// screenRenderer applies the rendered frames to a screen.
+type screenRenderer struct {
+	screen *Screen
+	frames []Frame
+}
+
+func (r *screenRenderer) Render(frame Frame) {
+	r.screen.Apply(frame, nil)
+	r.frames = append(r.frames, frame)
+}
+
+func (r *screenRenderer) Resize(size geometry.Point) {
+	r.screen.Resize(size)
+}
+
+func TestConsoleResize(t *testing.T) {
+	size := geometry.Point{X: 5, Y: 4}
+	r := &screenRenderer{screen: NewScreen(size)}
+	con := NewConsole(GridConfig{GridWidth: size.X, GridHeight: size.Y}, r)
+	con.ClearScreen()
+	con.Grid().Range().Iter(func(p geometry.Point) {
+		con.Set(p, common.Cell{Char: 'a' + rune(p.X+p.Y*size.X), Foreground: common.White, Background: common.Black})
+	})
+	con.Flush()
+
+	check := func(size geometry.Point) {
+		t.Helper()
+		r.frames = nil
+		con.Flush()
+		if con.Size() != size {
+			t.Fatalf("console size = %v, want %v", con.Size(), size)
+		}
+		if len(r.frames) != 1 || !r.frames[0].IsFullRedraw || len(r.frames[0].Cells) != size.X*size.Y {
+			t.Fatalf("frames after Resize = %+v, want a full redraw of %d cells", r.frames, size.X*size.Y)
+		}
+		if r.screen.Size() != size {
+			t.Fatalf("renderer size = %v, want %v", r.screen.Size(), size)
+		}
+		con.Grid().Iter(func(p geometry.Point, cell common.Cell) {
+			if got := r.screen.Square.At(p); got != cell {
+				t.Errorf("renderer cell %v = %q, want %q", p, got.Char, cell.Char)
+			}
+		})
+	}
+
+	con.Resize(geometry.Point{X: 3, Y: 2})
+	check(geometry.Point{X: 3, Y: 2})
+	if got := con.At(geometry.Point{X: 2, Y: 1}).Char; got != 'h' {
+		t.Errorf("kept cell = %q, want 'h'", got)
+	}
+
+	con.Resize(geometry.Point{X: 6, Y: 3})
+	check(geometry.Point{X: 6, Y: 3})
+	for _, p := range []geometry.Point{{X: 3, Y: 0}, {X: 4, Y: 1}, {X: 0, Y: 2}, {X: 5, Y: 2}} {
+		if got := con.At(p).Char; got != ' ' {
+			t.Errorf("new cell %v = %q, want a blank", p, got)
+		}
+	}
+	if got := con.At(geometry.Point{X: 1, Y: 1}).Char; got != 'g' {
+		t.Errorf("kept cell = %q, want 'g'", got)
+	}
+
+	// the delta frames work at the new size
+	r.frames = nil
+	con.Set(geometry.Point{X: 5, Y: 2}, common.Cell{Char: '@', Foreground: common.White, Background: common.Black})
+	con.Flush()
+	if len(r.frames) != 1 || len(r.frames[0].Cells) != 1 || r.frames[0].Cells[0].P != (geometry.Point{X: 5, Y: 2}) {
+		t.Errorf("frames = %+v, want one changed cell at (5,2)", r.frames)
+	}
+}
+
+// TestConsoleResizeLayers checks the delta frames after a shrinking Resize of
+// a console with layers, whose composed grid is smaller than the underlying
+// grid of the console.
+func TestConsoleResizeLayers(t *testing.T) {
+	size := geometry.Point{X: 5, Y: 4}
+	r := &screenRenderer{screen: NewScreen(size)}
+	con := NewConsole(GridConfig{GridWidth: size.X, GridHeight: size.Y}, r)
+	ui := con.AddLayer(LayerUI, ZUI)
+	fill := func(first rune) {
+		con.Grid().Range().Iter(func(p geometry.Point) {
+			con.Set(p, common.Cell{Char: first + rune(p.X+p.Y*con.Size().X), Foreground: common.White, Background: common.Black})
+		})
+	}
+	check := func() {
+		t.Helper()
+		con.Grid().Range().Iter(func(p geometry.Point) {
+			want := ComposeCell(con.At(p), ui.At(p))
+			if got := r.screen.Square.At(p); got != want {
+				t.Errorf("renderer cell %v = %q, want %q", p, got.Char, want.Char)
+			}
+		})
+	}
+	fill('a')
+	con.Flush()
+
+	con.Resize(geometry.Point{X: 3, Y: 2})
+	con.Flush()
+	check()
+	// the same runes at the new stride: the cells moving to another index of
+	// the underlying grid have to be sent
+	fill('a')
+	con.Flush()
+	check()
+	ui.Set(geometry.Point{X: 1, Y: 1}, common.Cell{Char: '@'})
+	con.Flush()
+	check()
+	ui.Clear()
+	con.Flush()
+	check()
+}
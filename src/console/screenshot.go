@@ -0,0 +1,65 @@
+package console
+
+import (
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// CellPainter paints cells onto standard library images, like
+// offscreen.Painter. It is used to take screenshots without a display.
+type CellPainter interface {
+	// Bounds returns the pixel bounds of a grid of the given size in cells.
+	Bounds(size geometry.Point) image.Rectangle
+	// DrawCell paints a square cell at the given grid position.
+	DrawCell(dst draw.Image, pos geometry.Point, cell common.Cell)
+	// DrawHalves paints two half-width cells in place of the square cell at
+	// the given grid position.
+	DrawHalves(dst draw.Image, pos geometry.Point, left, right common.Cell)
+}
+
+// Screenshot renders what the console currently holds, including the layers
+// and the half-width cells, to a new image. The cells drawn since the last
+// Flush are included. Colors go through the same tone mapping as on screen,
+// since the painters use the colors' RGBA methods.
+func (c *Console) Screenshot(painter CellPainter) *image.RGBA {
+	screen := &Screen{Square: c.composite(), HalfWidth: c.halfGrid}
+	if screen.HalfWidth.Ug == nil {
+		size := SquareToHalfWidth(screen.Square.Size())
+		screen.HalfWidth = geometry.NewGrid(size.X, size.Y)
+		screen.HalfWidth.Fill(TransparentCell)
+	}
+	img := image.NewRGBA(painter.Bounds(screen.Size()))
+	screen.Paint(img, painter)
+	return img
+}
+
+// WriteScreenshot writes a screenshot of the console as PNG.
+func (c *Console) WriteScreenshot(w io.Writer, painter CellPainter) error {
+	return png.Encode(w, c.Screenshot(painter))
+}
+
+// Paint paints the whole screen.
+func (s *Screen) Paint(dst draw.Image, painter CellPainter) {
+	s.Square.Range().Iter(func(p geometry.Point) {
+		s.PaintCell(dst, painter, p)
+	})
+}
+
+// PaintCell paints the square cell at p, or the two half-width cells
+// displayed in its place.
+func (s *Screen) PaintCell(dst draw.Image, painter CellPainter, p geometry.Point) {
+	if !s.Square.Contains(p) {
+		return
+	}
+	if s.HasHalfWidth(p) {
+		left, right := s.Halves(p)
+		painter.DrawHalves(dst, p, left, right)
+		return
+	}
+	painter.DrawCell(dst, p, s.Square.At(p))
+}
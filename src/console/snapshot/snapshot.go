@@ -0,0 +1,277 @@
+// Package snapshot is a golden file harness for models. It drives a model on
+// a headless engine through a scripted sequence of inputs, then compares the
+// displayed grids with a snapshot stored in the testdata directory of the
+// calling package:
+//
+//	var update = flag.Bool("update", false, "rewrite the golden snapshot files")
+//
+//	func TestPlaceWall(t *testing.T) {
+//		engine := headless.NewEngine(config)
+//		model := game.NewModel(config)
+//		snapshot.Run(engine, model,
+//			snapshot.MoveMouse(geometry.Point{X: 3, Y: 2}),
+//			snapshot.ClickLeft(geometry.Point{X: 5, Y: 5}),
+//		)
+//		snapshot.Match(t, "place_wall", engine.Renderer.Screen())
+//	}
+//
+// The headless engine calls the Init method of the model before its first
+// Update, so the test must not call it. Running the tests with -update
+// rewrites the snapshots instead of comparing them, when the test package
+// defines the flag as above, see Update.
+//
+// A snapshot has a section with the runes of the square grid, as returned by
+// Grid.String, and a section with a code per cell naming its colors, the
+// codes being listed in a legend. The codes are letters or digits, and have
+// more than one character in grids with more than 62 color pairs. The
+// half-width cells have the same two sections when some of them are not
+// transparent.
+package snapshot
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/headless"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// Update makes Match rewrite the snapshots instead of comparing them. When it
+// is false, Match still rewrites them if the test binary defines a boolean
+// -update flag and it is set. The package doesn't define the flag itself, as
+// a test binary defining it too would panic.
+var Update bool
+
+// Dir is the directory of the snapshot files, relative to the directory of the
+// package under test.
+var Dir = "testdata"
+
+// Step is the input of one frame of a script. A nil Step is an idle frame.
+type Step func(in *headless.Input)
+
+// Run executes one engine step per script step, with the input set by the
+// script step. The engine initializes the model on its first step.
+func Run(engine *headless.Engine, model console.Model, script ...Step) {
+	for _, step := range script {
+		if step != nil {
+			step(engine.Input)
+		}
+		engine.Step(model)
+	}
+}
+
+// Keys presses the given keys, see headless.Input for their names.
+func Keys(keys ...string) Step {
+	return func(in *headless.Input) {
+		in.PressKeys(keys...)
+	}
+}
+
+// MoveMouse moves the mouse to a square cell.
+func MoveMouse(p geometry.Point) Step {
+	return func(in *headless.Input) {
+		in.MoveMouse(p)
+	}
+}
+
+// ClickLeft clicks the left mouse button on a square cell.
+func ClickLeft(p geometry.Point) Step {
+	return func(in *headless.Input) {
+		in.ClickLeft(p)
+	}
+}
+
+// ClickRight clicks the right mouse button on a square cell.
+func ClickRight(p geometry.Point) Step {
+	return func(in *headless.Input) {
+		in.ClickRight(p)
+	}
+}
+
+// Idle returns n idle frames, to be expanded in a script with "...".
+func Idle(n int) []Step {
+	return make([]Step, n)
+}
+
+// Match compares the screen with the snapshot of the given name, or rewrites
+// the snapshot when the tests run with -update. A missing snapshot is an
+// error.
+func Match(t testing.TB, name string, screen *console.Screen) {
+	t.Helper()
+	got := Encode(screen)
+	path := filepath.Join(Dir, name+".golden")
+	if updating() {
+		if err := os.MkdirAll(Dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("missing snapshot %s, run the tests with -update to create it", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := Diff(string(want), got); diff != "" {
+		t.Errorf("screen differs from snapshot %s (-want +got):\n%s", path, diff)
+	}
+}
+
+func updating() bool {
+	if Update {
+		return true
+	}
+	f := flag.Lookup("update")
+	if f == nil {
+		return false
+	}
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	value, ok := getter.Get().(bool)
+	return ok && value
+}
+
+// Encode returns the snapshot text of a screen.
+func Encode(screen *console.Screen) string {
+	var b strings.Builder
+	encodeGrid(&b, "", screen.Square)
+	if hasHalfWidth(screen) {
+		encodeGrid(&b, "half-width ", screen.HalfWidth)
+	}
+	return b.String()
+}
+
+func encodeGrid(b *strings.Builder, prefix string, gd geometry.Grid) {
+	// transparent half-width cells have no rune, they are shown as spaces
+	fmt.Fprintf(b, "%srunes:\n%s", prefix, strings.ReplaceAll(gd.String(), "\x00", " "))
+	// the color pairs are numbered in the order of their first cell
+	legend := make(map[cellColors]int)
+	var order []cellColors
+	gd.Iter(func(p geometry.Point, cell common.Cell) {
+		colors := cellColors{fg: hex(cell.Foreground), bg: hex(cell.Background)}
+		if _, ok := legend[colors]; !ok {
+			legend[colors] = len(order)
+			order = append(order, colors)
+		}
+	})
+	width := legendCodeWidth(len(order))
+	fmt.Fprintf(b, "%scolors:\n", prefix)
+	gd.Iter(func(p geometry.Point, cell common.Cell) {
+		colors := cellColors{fg: hex(cell.Foreground), bg: hex(cell.Background)}
+		b.WriteString(legendCode(legend[colors], width))
+		if p.X == gd.Size().X-1 {
+			b.WriteByte('\n')
+		}
+	})
+	fmt.Fprintf(b, "%slegend:\n", prefix)
+	for i, colors := range order {
+		fmt.Fprintf(b, "%s fg=%s bg=%s\n", legendCode(i, width), colors.fg, colors.bg)
+	}
+}
+
+func hasHalfWidth(screen *console.Screen) bool {
+	transparent := true
+	screen.HalfWidth.Iter(func(p geometry.Point, cell common.Cell) {
+		if cell != console.TransparentCell {
+			transparent = false
+		}
+	})
+	return !transparent
+}
+
+type cellColors struct {
+	fg, bg string
+}
+
+const legendLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// legendCodeWidth returns the number of characters of the codes of a grid
+// with the given number of color pairs. All the codes of a grid have the same
+// width, so that its rows stay aligned.
+func legendCodeWidth(pairs int) int {
+	width := 1
+	for n := len(legendLetters); n < pairs; n *= len(legendLetters) {
+		width++
+	}
+	return width
+}
+
+// legendCode returns the code of the i-th color pair of a grid, with the
+// given number of characters.
+func legendCode(i, width int) string {
+	code := make([]byte, width)
+	for k := width - 1; k >= 0; k-- {
+		code[k] = legendLetters[i%len(legendLetters)]
+		i /= len(legendLetters)
+	}
+	return string(code)
+}
+
+// hex returns the displayed color, after tone mapping, as #rrggbbaa, or "-"
+// for a nil color.
+func hex(c color.Color) string {
+	if c == nil {
+		return "-"
+	}
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	return fmt.Sprintf("#%02x%02x%02x%02x", rgba.R, rgba.G, rgba.B, rgba.A)
+}
+
+// Diff compares two snapshots line by line and returns a readable report of
+// the differing lines, with a marker under the differing columns, or "" if
+// they are equal.
+func Diff(want, got string) string {
+	if want == got {
+		return ""
+	}
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		w, g := lineAt(wantLines, i), lineAt(gotLines, i)
+		if w == g {
+			continue
+		}
+		fmt.Fprintf(&b, "line %d:\n- %s\n+ %s\n  %s\n", i+1, w, g, marker(w, g))
+	}
+	return b.String()
+}
+
+func lineAt(lines []string, i int) string {
+	if i < len(lines) {
+		return lines[i]
+	}
+	return ""
+}
+
+// marker returns a line with a '^' under each rune that differs.
+func marker(a, b string) string {
+	ra, rb := []rune(a), []rune(b)
+	n := len(ra)
+	if len(rb) > n {
+		n = len(rb)
+	}
+	m := make([]rune, n)
+	for i := range m {
+		m[i] = ' '
+		if i >= len(ra) || i >= len(rb) || ra[i] != rb[i] {
+			m[i] = '^'
+		}
+	}
+	return strings.TrimRight(string(m), " ")
+}
@@ -0,0 +1,208 @@
+package snapshot
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/headless"
+	"github.com/memmaker/ECon/game"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// the package must not define -update itself, or this would panic
+var update = flag.Bool("update", false, "rewrite the golden snapshot files")
+
+func TestUpdateFlag(t *testing.T) {
+	defer func(value bool) { *update = value }(*update)
+	*update = false
+	if updating() {
+		t.Error("updating() = true with -update=false")
+	}
+	if err := flag.Set("update", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if !updating() {
+		t.Error("updating() = false with -update=true")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	screen := console.NewScreen(geometry.Point{X: 3, Y: 2})
+	screen.Clear()
+	screen.Square.Set(geometry.Point{X: 1}, common.Cell{Char: '@', Foreground: common.RGBColor{R: 1}, Background: common.Black})
+	want := `runes:
+ @ 
+   
+colors:
+aba
+aaa
+legend:
+a fg=#a1a1a1ff bg=#000000ff
+b fg=#a10000ff bg=#000000ff
+`
+	if got := Encode(screen); got != want {
+		t.Errorf("Encode() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEncodeHalfWidth(t *testing.T) {
+	screen := console.NewScreen(geometry.Point{X: 1, Y: 1})
+	screen.Clear()
+	screen.HalfWidth.Set(geometry.Point{X: 1}, common.Cell{Char: 'x', Foreground: common.White, Background: common.Black})
+	got := Encode(screen)
+	if !strings.Contains(got, "half-width runes:\n x\n") || !strings.Contains(got, "half-width legend:\n") {
+		t.Errorf("Encode() has no half-width sections:\n%s", got)
+	}
+}
+
+func TestLegendCodes(t *testing.T) {
+	tests := []struct {
+		pairs, width int
+	}{
+		{1, 1}, {62, 1}, {63, 2}, {62 * 62, 2}, {62*62 + 1, 3},
+	}
+	for _, tt := range tests {
+		if width := legendCodeWidth(tt.pairs); width != tt.width {
+			t.Errorf("legendCodeWidth(%d) = %d, want %d", tt.pairs, width, tt.width)
+		}
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 62*62; i++ {
+		code := legendCode(i, 2)
+		if len(code) != 2 || seen[code] {
+			t.Fatalf("legendCode(%d, 2) = %q is not a new 2 characters code", i, code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestEncodeManyColors(t *testing.T) {
+	const pairs = 70
+	screen := console.NewScreen(geometry.Point{X: 10, Y: 7})
+	screen.Square.Range().Iter(func(p geometry.Point) {
+		i := p.Y*10 + p.X
+		screen.Square.Set(p, common.Cell{Char: 'x', Foreground: common.RGBColor{R: float64(i) / pairs}, Background: common.Black})
+	})
+	got := Encode(screen)
+	colors := section(got, "colors:", "legend:")
+	for _, row := range colors {
+		if len(row) != 20 {
+			t.Errorf("color row %q has %d characters, want 20", row, len(row))
+		}
+	}
+	codes := make(map[string]bool)
+	for _, line := range section(got, "legend:", "") {
+		codes[strings.Fields(line)[0]] = true
+	}
+	if len(codes) != pairs {
+		t.Errorf("legend has %d codes, want %d", len(codes), pairs)
+	}
+}
+
+// section returns the lines of a snapshot between two headers, the end
+// header being excluded. An empty end means the end of the snapshot.
+func section(snapshot, start, end string) []string {
+	var lines []string
+	in := false
+	for _, line := range strings.Split(strings.TrimSuffix(snapshot, "\n"), "\n") {
+		switch {
+		case line == start:
+			in = true
+		case line == end:
+			in = false
+		case in:
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestDiff(t *testing.T) {
+	if diff := Diff("a\nb\n", "a\nb\n"); diff != "" {
+		t.Errorf("Diff of equal snapshots = %q, want \"\"", diff)
+	}
+	want := "line 2:\n- abc\n+ axc\n   ^\n"
+	if diff := Diff("a\nabc\n", "a\naxc\n"); diff != want {
+		t.Errorf("Diff() = %q, want %q", diff, want)
+	}
+}
+
+// recorder is a testing.TB recording the failures of Match. Fatalf stops the
+// goroutine like testing.T does.
+type recorder struct {
+	testing.TB
+	failures []string
+	fatal    bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatal(args ...any) {
+	r.Fatalf("%s", fmt.Sprint(args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+	r.fatal = true
+	runtime.Goexit()
+}
+
+// match runs Match with a recorder.
+func match(name string, screen *console.Screen) *recorder {
+	r := &recorder{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Match(r, name, screen)
+	}()
+	<-done
+	return r
+}
+
+func TestMatch(t *testing.T) {
+	defer func(dir string, value bool) { Dir, *update = dir, value }(Dir, *update)
+	Dir, *update = t.TempDir(), false
+	config := console.GridConfig{GridWidth: 16, GridHeight: 8}
+	engine := headless.NewEngine(config)
+	Run(engine, game.NewModel(config),
+		MoveMouse(geometry.Point{X: 3, Y: 2}),
+		ClickLeft(geometry.Point{X: 5, Y: 5}),
+	)
+	screen := engine.Renderer.Screen()
+
+	if r := match("wall", screen); !r.fatal || !strings.Contains(r.failures[0], "missing snapshot") {
+		t.Fatalf("Match without a snapshot failed with %q, want a missing snapshot", r.failures)
+	}
+	Update = true
+	r := match("wall", screen)
+	Update = false
+	if len(r.failures) != 0 {
+		t.Fatalf("Match with Update failed: %q", r.failures)
+	}
+	data, err := os.ReadFile(filepath.Join(Dir, "wall.golden"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != Encode(screen) {
+		t.Errorf("written snapshot =\n%s\nwant\n%s", data, Encode(screen))
+	}
+	if r := match("wall", screen); len(r.failures) != 0 {
+		t.Errorf("Match of the same screen failed: %q", r.failures)
+	}
+
+	Run(engine, game.NewModel(config), Keys("C"))
+	if r := match("wall", engine.Renderer.Screen()); r.fatal || len(r.failures) != 1 {
+		t.Errorf("Match of another screen failed with %q, want one difference", r.failures)
+	}
+}
@@ -0,0 +1,165 @@
+package terminal
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/ansi"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/input"
+)
+
+// Engine is a console.Engine running the game loop in a terminal. When the
+// reader and writer are terminals, Start switches the input to raw mode and
+// the terminal size is checked on every Step: the console is resized to fill
+// the terminal, a square cell taking two columns, and the model is told with
+// OnResize if it implements console.ResizeListener.
+type Engine struct {
+	Input    *Input
+	Console  *console.Console
+	Renderer *Renderer
+
+	in           io.Reader
+	out          io.Writer
+	restore      func()
+	terminalSize func() (geometry.Point, bool) // size of out, if a terminal
+	size         geometry.Point
+	sizeChanged  bool // size changed since the last Step
+	resized      bool
+}
+
+func NewEngine(config console.GridConfig, in io.Reader, out io.Writer, mode ansi.ColorMode) *Engine {
+	renderer := NewRenderer(out, config, mode)
+	e := &Engine{
+		Input:    NewInput(),
+		Console:  console.NewConsole(config, renderer),
+		Renderer: renderer,
+		in:       in,
+		out:      out,
+		restore:  func() {},
+	}
+	e.terminalSize = e.queryTerminalSize
+	return e
+}
+
+func (e *Engine) GetInput() input.GridInput {
+	return e.Input
+}
+
+// Start prepares the terminal and starts reading the input.
+func (e *Engine) Start() error {
+	if f, ok := e.in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		state, err := term.MakeRaw(int(f.Fd()))
+		if err != nil {
+			return err
+		}
+		e.restore = func() { term.Restore(int(f.Fd()), state) }
+	}
+	e.Input.StartReading(e.in)
+	e.checkSize()
+	return e.Renderer.Start()
+}
+
+// Stop restores the terminal. The reading goroutine stays blocked on the
+// reader until it returns.
+func (e *Engine) Stop() error {
+	err := e.Renderer.Stop()
+	e.restore()
+	e.restore = func() {}
+	return err
+}
+
+// Resized reports whether the terminal size changed during the last Step,
+// resizing the console.
+func (e *Engine) Resized() bool {
+	return e.resized
+}
+
+// TerminalSize returns the last known terminal size in cells.
+func (e *Engine) TerminalSize() geometry.Point {
+	return e.size
+}
+
+// Step runs one iteration of the game loop: it applies the terminal size,
+// polls the input, calls the model's Update and Draw, and flushes the console.
+func (e *Engine) Step(model console.Model) error {
+	e.resized = false
+	e.checkSize()
+	if e.sizeChanged {
+		e.sizeChanged = false
+		e.resize(model)
+	}
+	if err := e.Input.Poll(); err != nil && err != io.EOF {
+		return err
+	}
+	model.Update(e)
+	model.Draw(e.Console)
+	e.Console.Flush()
+	e.Input.EndFrame()
+	return e.Renderer.Err()
+}
+
+// Run calls Step every frameDuration until Ctrl+C is typed, the input ends or
+// an error occurs. The terminal is restored before returning.
+func (e *Engine) Run(model console.Model, frameDuration time.Duration) error {
+	if err := e.Start(); err != nil {
+		e.restore()
+		return err
+	}
+	ticker := time.NewTicker(frameDuration)
+	defer ticker.Stop()
+	var err error
+	for !e.Input.IsInterrupted() && e.Input.chunks != nil {
+		if err = e.Step(model); err != nil {
+			break
+		}
+		<-ticker.C
+	}
+	if stopErr := e.Stop(); err == nil {
+		err = stopErr
+	}
+	return err
+}
+
+// checkSize updates the terminal size. A change is applied by the next Step.
+func (e *Engine) checkSize() {
+	size, ok := e.terminalSize()
+	if !ok || size == e.size {
+		return
+	}
+	e.sizeChanged = true
+	e.resized = e.resized || e.size != geometry.Point{}
+	e.size = size
+	e.Renderer.SetSize(size)
+}
+
+// resize resizes the console to the terminal size and tells the model. The
+// terminal is repainted even if the console keeps its size, as terminals
+// don't reliably keep the content on resize.
+func (e *Engine) resize(model console.Model) {
+	size := geometry.Point{X: e.size.X / 2, Y: e.size.Y}
+	if size.X < 1 || size.Y < 1 || size == e.Console.Size() {
+		e.Renderer.Repaint()
+		return
+	}
+	e.Console.Resize(size)
+	if listener, ok := model.(console.ResizeListener); ok {
+		listener.OnResize(e.Console.Size())
+	}
+}
+
+func (e *Engine) queryTerminalSize() (geometry.Point, bool) {
+	f, ok := e.out.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return geometry.Point{}, false
+	}
+	w, h, err := term.GetSize(int(f.Fd()))
+	if err != nil {
+		return geometry.Point{}, false
+	}
+	return geometry.Point{X: w, Y: h}, true
+}
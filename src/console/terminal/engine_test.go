@@ -0,0 +1,80 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/ansi"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// resizeModel draws its size in its top left cell and records the sizes
+// passed to OnResize.
+type resizeModel struct {
+	resizes []geometry.Point
+	updates int
+}
+
+func (m *resizeModel) OnResize(size geometry.Point) {
+	m.resizes = append(m.resizes, size)
+}
+
+func (m *resizeModel) Update(engine console.Engine) {
+	m.updates++
+}
+
+func (m *resizeModel) Draw(con console.CellInterface) {
+	con.Set(geometry.Point{}, common.Cell{Char: rune('0' + con.Size().X), Foreground: common.White, Background: common.Black})
+}
+
+func TestEngineResize(t *testing.T) {
+	var out bytes.Buffer
+	config := console.GridConfig{TileWidth: 1, TileHeight: 1, GridWidth: 4, GridHeight: 3}
+	e := NewEngine(config, strings.NewReader(""), &out, ansi.TrueColor)
+	terminalSize := geometry.Point{X: 8, Y: 3}
+	e.terminalSize = func() (geometry.Point, bool) {
+		return terminalSize, true
+	}
+	if err := e.Start(); err != nil {
+		t.Fatal(err)
+	}
+	model := &resizeModel{}
+	step := func() {
+		t.Helper()
+		if err := e.Step(model); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	step()
+	if len(model.resizes) != 0 || e.Resized() {
+		t.Fatalf("resizes = %v, Resized() = %v for a terminal of the console size", model.resizes, e.Resized())
+	}
+	terminalSize = geometry.Point{X: 13, Y: 5}
+	out.Reset()
+	step()
+	want := geometry.Point{X: 6, Y: 5}
+	if !e.Resized() || len(model.resizes) != 1 || model.resizes[0] != want {
+		t.Fatalf("resizes = %v, Resized() = %v, want [%v], true", model.resizes, e.Resized(), want)
+	}
+	if size := e.Console.Size(); size != want {
+		t.Errorf("console size = %v, want %v", size, want)
+	}
+	if !strings.Contains(out.String(), ansi.ClearScreen) || !strings.Contains(out.String(), "6") {
+		t.Errorf("the terminal was not repainted at the new size: %q", out.String())
+	}
+	if e.Renderer.screen.Size() != want {
+		t.Errorf("renderer size = %v, want %v", e.Renderer.screen.Size(), want)
+	}
+
+	step()
+	if e.Resized() || len(model.resizes) != 1 {
+		t.Errorf("resizes = %v, Resized() = %v without a terminal size change", model.resizes, e.Resized())
+	}
+	if err := e.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
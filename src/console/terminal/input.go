@@ -0,0 +1,474 @@
+package terminal
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/input"
+)
+
+// Input is an input.GridInput reading raw terminal input: keys, escape
+// sequences for the special keys and SGR mouse reports (enabled by
+// Renderer.Start). Key names follow ebiten.Key.String(), so models see the
+// same names as with the ebiten backend.
+//
+// Like the other inputs, its state is valid for one frame: Poll collects the
+// bytes read since the last frame, and EndFrame resets the just pressed keys
+// and buttons.
+//
+// Terminals only report key presses: a held key is repeated by the terminal,
+// so the events of the keys are all KeyDown, without KeyUp or KeyRepeat. The
+// mouse buttons are reported with their releases and drags.
+type Input struct {
+	// Actions are the bindings of the menu actions, nil for the defaults of
+	// input.MenuActions.
+	Actions *input.ActionMap
+
+	mouseHalfPos geometry.Point // terminal cells are half-width cells
+	mousePos     geometry.Point
+	lastMousePos geometry.Point
+	mouseLeft    bool
+	mouseRight   bool
+	interrupted  bool
+	keys         []string
+	chars        []rune
+	modifiers    int // modifiers of the special keys of the frame, see splitModifier
+	wheel        int
+	events       input.EventQueue
+
+	pending []byte      // incomplete sequence kept for the next Parse
+	chunks  chan []byte // filled by the reading goroutine
+	readErr chan error
+}
+
+func NewInput() *Input {
+	return &Input{}
+}
+
+// StartReading starts a goroutine reading r until an error occurs. The bytes
+// read are parsed by the next call to Poll.
+func (i *Input) StartReading(r io.Reader) {
+	i.chunks = make(chan []byte, 64)
+	i.readErr = make(chan error, 1)
+	go func() {
+		for {
+			buf := make([]byte, 256)
+			n, err := r.Read(buf)
+			if n > 0 {
+				i.chunks <- buf[:n]
+			}
+			if err != nil {
+				i.readErr <- err
+				close(i.chunks)
+				return
+			}
+		}
+	}()
+}
+
+// Poll parses the bytes read since the last call without blocking. It
+// returns the error that stopped the reading goroutine, if any.
+//
+// A lone ESC kept by Parse is the Escape key if no byte followed it until
+// this Poll, or if the reading stopped.
+func (i *Input) Poll() error {
+	escape := i.isLoneEscape()
+	for {
+		select {
+		case chunk, ok := <-i.chunks:
+			if !ok {
+				i.chunks = nil
+				i.flushEscape()
+				return <-i.readErr
+			}
+			i.Parse(chunk)
+			escape = false
+		default:
+			if escape {
+				i.flushEscape()
+			}
+			return nil
+		}
+	}
+}
+
+// Parse updates the input state from raw terminal bytes. An escape sequence
+// cut at the end of data is kept until the next call. So is a lone ESC, which
+// may be the Escape key or the start of a sequence cut by the read, see Poll.
+func (i *Input) Parse(data []byte) {
+	data = append(i.pending, data...)
+	i.pending = nil
+	for len(data) > 0 {
+		n := i.parseOne(data)
+		if n == 0 {
+			i.pending = append([]byte(nil), data...)
+			return
+		}
+		data = data[n:]
+	}
+}
+
+func (i *Input) isLoneEscape() bool {
+	return len(i.pending) == 1 && i.pending[0] == 0x1b
+}
+
+// flushEscape reports the pending lone ESC as the Escape key.
+func (i *Input) flushEscape() {
+	if i.isLoneEscape() {
+		i.pending = nil
+		i.events.SetModifiers(0)
+		i.press("Escape")
+	}
+}
+
+// parseOne parses the event at the start of data and returns the number of
+// bytes consumed, or 0 if the sequence is incomplete.
+func (i *Input) parseOne(data []byte) int {
+	i.events.SetModifiers(0)
+	switch b := data[0]; {
+	case b == 0x1b:
+		if len(data) == 1 {
+			return 0
+		}
+		switch data[1] {
+		case '[':
+			return i.parseCSI(data)
+		case 'O':
+			if len(data) < 3 {
+				return 0
+			}
+			i.pressFinal(data[2])
+			return 3
+		default:
+			i.press("Escape")
+			return 1
+		}
+	case b == '\r' || b == '\n':
+		i.press("Enter")
+	case b == '\t':
+		i.press("Tab")
+	case b == 0x7f || b == 0x08:
+		i.press("Backspace")
+	case b == 0x03:
+		i.interrupted = true
+	case b >= 0x01 && b <= 0x1a:
+		// Ctrl+A to Ctrl+Z, except the ones above
+		i.setModifiers(modControl)
+		i.press(string('A' + b - 1))
+	case b < ' ':
+		// other control characters are ignored
+	case b < utf8.RuneSelf:
+		if name, ok := asciiKeyNames[b]; ok {
+			i.press(name)
+		}
+		i.typeChar(rune(b))
+	default:
+		if !utf8.FullRune(data) {
+			return 0
+		}
+		r, size := utf8.DecodeRune(data)
+		i.typeChar(r)
+		return size
+	}
+	return 1
+}
+
+// parseCSI parses a sequence starting with ESC [.
+func (i *Input) parseCSI(data []byte) int {
+	end := 2
+	for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+		end++
+	}
+	if end == len(data) {
+		return 0
+	}
+	params, final := data[2:end], data[end]
+	switch {
+	case len(params) > 0 && params[0] == '<' && (final == 'M' || final == 'm'):
+		i.parseMouse(params[1:], final == 'M')
+	case final == '~':
+		key, modifiers := splitModifier(params)
+		if name, ok := tildeKeyNames[key]; ok {
+			i.setModifiers(modifiers)
+			i.press(name)
+		}
+	default:
+		_, modifiers := splitModifier(params)
+		i.setModifiers(modifiers)
+		i.pressFinal(final)
+	}
+	return end + 1
+}
+
+// modifier bits of the special key sequences
+const (
+	modShift = 1 << iota
+	modAlt
+	modControl
+)
+
+// splitModifier splits the parameters "key;modifier" of a special key. The
+// modifier is 1 plus a mask of the mod bits.
+func splitModifier(params []byte) (key string, modifiers int) {
+	fields := bytes.SplitN(params, []byte{';'}, 2)
+	if len(fields) < 2 {
+		return string(params), 0
+	}
+	modifier, err := strconv.Atoi(string(fields[1]))
+	if err != nil || modifier < 1 {
+		return string(fields[0]), 0
+	}
+	return string(fields[0]), modifier - 1
+}
+
+// setModifiers adds the mod bits of a key to the modifiers of the frame, and
+// sets them for its events.
+func (i *Input) setModifiers(modifiers int) {
+	i.modifiers |= modifiers
+	var mods input.Modifiers
+	if modifiers&modShift != 0 {
+		mods |= input.ModShift
+	}
+	if modifiers&modAlt != 0 {
+		mods |= input.ModAlt
+	}
+	if modifiers&modControl != 0 {
+		mods |= input.ModControl
+	}
+	i.events.SetModifiers(mods)
+}
+
+// parseMouse parses the parameters of an SGR mouse report "b;x;y".
+func (i *Input) parseMouse(params []byte, press bool) {
+	fields := bytes.Split(params, []byte{';'})
+	if len(fields) != 3 {
+		return
+	}
+	var values [3]int
+	for k, field := range fields {
+		v, err := strconv.Atoi(string(field))
+		if err != nil {
+			return
+		}
+		values[k] = v
+	}
+	button, x, y := values[0], values[1]-1, values[2]-1
+	const shift, alt, control, motion, wheel = 4, 8, 16, 32, 64
+	var mods input.Modifiers
+	if button&shift != 0 {
+		mods |= input.ModShift
+	}
+	if button&alt != 0 {
+		mods |= input.ModAlt
+	}
+	if button&control != 0 {
+		mods |= input.ModControl
+	}
+	i.events.SetModifiers(mods)
+	i.mouseHalfPos = geometry.Point{X: x, Y: y}
+	i.mousePos = console.HalfWidthToSquare(i.mouseHalfPos)
+	i.events.MouseMove(i.mousePos, i.mouseHalfPos)
+	if button&wheel != 0 {
+		// buttons 4 and 5 are reported as wheel + 0 and wheel + 1
+		if press && button&motion == 0 {
+			notches := 1
+			if button&1 != 0 {
+				notches = -1
+			}
+			i.wheel += notches
+			i.events.Wheel(notches)
+		}
+		return
+	}
+	if button&motion != 0 {
+		// moves, with or without a held button
+		return
+	}
+	var b input.MouseButton
+	switch button & 3 {
+	case 0:
+		b = input.ButtonLeft
+	case 1:
+		b = input.ButtonMiddle
+	case 2:
+		b = input.ButtonRight
+	default:
+		return
+	}
+	if !press {
+		i.events.MouseUp(b)
+		return
+	}
+	switch b {
+	case input.ButtonLeft:
+		i.mouseLeft = true
+	case input.ButtonRight:
+		i.mouseRight = true
+	}
+	i.events.MouseDown(b)
+}
+
+func (i *Input) pressFinal(final byte) {
+	if name, ok := finalKeyNames[final]; ok {
+		i.press(name)
+	}
+}
+
+func (i *Input) press(key string) {
+	i.keys = append(i.keys, key)
+	i.events.KeyDown(key)
+}
+
+func (i *Input) typeChar(r rune) {
+	i.chars = append(i.chars, r)
+	i.events.Char(r)
+}
+
+var finalKeyNames = map[byte]string{
+	'A': "ArrowUp",
+	'B': "ArrowDown",
+	'C': "ArrowRight",
+	'D': "ArrowLeft",
+	'H': "Home",
+	'F': "End",
+}
+
+var tildeKeyNames = map[string]string{
+	"1": "Home",
+	"2": "Insert",
+	"3": "Delete",
+	"4": "End",
+	"5": "PageUp",
+	"6": "PageDown",
+}
+
+var asciiKeyNames = map[byte]string{
+	' ':  "Space",
+	'\'': "Quote",
+	',':  "Comma",
+	'-':  "Minus",
+	'.':  "Period",
+	'/':  "Slash",
+	';':  "Semicolon",
+	'=':  "Equal",
+	'[':  "BracketLeft",
+	'\\': "Backslash",
+	']':  "BracketRight",
+	'`':  "Backquote",
+}
+
+func init() {
+	for c := byte('a'); c <= 'z'; c++ {
+		asciiKeyNames[c] = string(c - 'a' + 'A')
+		asciiKeyNames[c-'a'+'A'] = string(c - 'a' + 'A')
+	}
+	for c := byte('0'); c <= '9'; c++ {
+		asciiKeyNames[c] = "Digit" + string(c)
+	}
+}
+
+// EndFrame resets the per frame state.
+func (i *Input) EndFrame() {
+	i.lastMousePos = i.mousePos
+	i.mouseLeft = false
+	i.mouseRight = false
+	i.keys = i.keys[:0]
+	i.chars = i.chars[:0]
+	i.modifiers = 0
+	i.wheel = 0
+	i.events.Clear()
+}
+
+// GetWheel implements input.WheelInput.
+func (i *Input) GetWheel() int {
+	return i.wheel
+}
+
+// IsShiftDown implements input.ModifierInput. Terminals only report the
+// modifiers together with the special keys, like the arrows, and Control with
+// the letters, so they are only known in the frames where such a key was
+// pressed. They are those of all the keys of the frame: the KeyDown events
+// have the modifiers of each key, which the bindings use.
+func (i *Input) IsShiftDown() bool {
+	return i.modifiers&modShift != 0
+}
+
+func (i *Input) IsControlDown() bool {
+	return i.modifiers&modControl != 0
+}
+
+func (i *Input) IsAltDown() bool {
+	return i.modifiers&modAlt != 0
+}
+
+// IsInterrupted reports whether Ctrl+C was typed. In raw mode, the terminal
+// doesn't send SIGINT anymore.
+func (i *Input) IsInterrupted() bool {
+	return i.interrupted
+}
+
+func (i *Input) GetMousePos() geometry.Point {
+	return i.mousePos
+}
+
+func (i *Input) GetHalfWidthMousePos() geometry.Point {
+	return i.mouseHalfPos
+}
+
+func (i *Input) HasMouseMoved() bool {
+	return i.mousePos != i.lastMousePos
+}
+
+func (i *Input) IsMouseLeft() bool {
+	return i.mouseLeft
+}
+
+func (i *Input) IsMouseRight() bool {
+	return i.mouseRight
+}
+
+func (i *Input) IsMenuClose() bool {
+	return input.IsMenuTriggered(i, i.Actions, input.MenuClose)
+}
+
+func (i *Input) IsMenuConfirm() bool {
+	return input.IsMenuTriggered(i, i.Actions, input.MenuConfirm)
+}
+
+func (i *Input) IsMenuDown() bool {
+	return input.IsMenuTriggered(i, i.Actions, input.MenuDown)
+}
+
+func (i *Input) IsMenuUp() bool {
+	return input.IsMenuTriggered(i, i.Actions, input.MenuUp)
+}
+
+func (i *Input) GetJustPressedKeys() []string {
+	keys := make([]string, len(i.keys))
+	copy(keys, i.keys)
+	return keys
+}
+
+func (i *Input) GetInputChars() []rune {
+	chars := make([]rune, len(i.chars))
+	copy(chars, i.chars)
+	return chars
+}
+
+func (i *Input) GetEvents() []input.Event {
+	return i.events.Events()
+}
+
+// GetJustPressedGamepadButtons returns nil: terminals don't read gamepads.
+func (i *Input) GetJustPressedGamepadButtons() []string {
+	return nil
+}
+
+// GetGamepadDirection returns the zero point: terminals don't read gamepads.
+func (i *Input) GetGamepadDirection() geometry.Point {
+	return geometry.Point{}
+}
@@ -0,0 +1,140 @@
+package terminal
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/memmaker/ECon/input"
+)
+
+// readAll starts reading r and polls until its end. It returns the keys
+// pressed.
+func readAll(t *testing.T, in *Input, r io.Reader) []string {
+	t.Helper()
+	in.StartReading(r)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		err := in.Poll()
+		if err == io.EOF {
+			return in.GetJustPressedKeys()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the input did not end")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestInputKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		keys []string
+	}{
+		{"letters", "aB", []string{"A", "B"}},
+		{"arrows", "\x1b[A\x1bOB", []string{"ArrowUp", "ArrowDown"}},
+		{"escape before a key", "\x1bw", []string{"Escape", "W"}},
+		{"escape at the end", "w\x1b", []string{"W", "Escape"}},
+		{"tilde keys", "\x1b[3~\x1b[5;5~", []string{"Delete", "PageUp"}},
+		{"control letter", "\x01", []string{"A"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := readAll(t, NewInput(), strings.NewReader(tt.data))
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("keys = %q, want %q", keys, tt.keys)
+			}
+		})
+	}
+}
+
+func TestInputSplitSequence(t *testing.T) {
+	in := NewInput()
+	in.Parse([]byte("\x1b"))
+	if keys := in.GetJustPressedKeys(); len(keys) != 0 {
+		t.Fatalf("keys after a lone ESC = %q, want none", keys)
+	}
+	in.Parse([]byte("[B"))
+	if keys := in.GetJustPressedKeys(); !reflect.DeepEqual(keys, []string{"ArrowDown"}) {
+		t.Errorf("keys = %q, want [ArrowDown]", keys)
+	}
+}
+
+func TestInputPendingEscape(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string // read before each Poll
+		keys   []string // pressed after each Poll
+	}{
+		{"escape key", []string{"\x1b", ""}, []string{"", "Escape"}},
+		{"sequence cut by the read", []string{"\x1b", "[A"}, []string{"", "ArrowUp"}},
+		{"two escape keys", []string{"\x1b", "\x1b", ""}, []string{"", "Escape", "Escape"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := NewInput()
+			in.chunks = make(chan []byte, 1)
+			for k, chunk := range tt.chunks {
+				if chunk != "" {
+					in.chunks <- []byte(chunk)
+				}
+				if err := in.Poll(); err != nil {
+					t.Fatal(err)
+				}
+				if keys := strings.Join(in.GetJustPressedKeys(), " "); keys != tt.keys[k] {
+					t.Errorf("keys after Poll %d = %q, want %q", k, keys, tt.keys[k])
+				}
+				in.EndFrame()
+			}
+		})
+	}
+}
+
+func TestInputMouse(t *testing.T) {
+	in := NewInput()
+	// press and release the left button at column 5, row 3, with Control
+	in.Parse([]byte("\x1b[<16;5;3M\x1b[<16;5;3m"))
+	if !in.IsMouseLeft() {
+		t.Error("IsMouseLeft = false, want true")
+	}
+	if pos := in.GetHalfWidthMousePos(); pos.X != 4 || pos.Y != 2 {
+		t.Errorf("half-width mouse position = %v, want (4,2)", pos)
+	}
+	if pos := in.GetMousePos(); pos.X != 2 || pos.Y != 2 {
+		t.Errorf("mouse position = %v, want (2,2)", pos)
+	}
+	var kinds []input.EventKind
+	for _, ev := range in.GetEvents() {
+		kinds = append(kinds, ev.Kind)
+		if !ev.Mods.Has(input.ModControl) {
+			t.Errorf("%v event without Control", ev.Kind)
+		}
+	}
+	want := []input.EventKind{input.MouseMove, input.MouseDown, input.MouseUp}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("events = %v, want %v", kinds, want)
+	}
+}
+
+func TestInputModifiersPerKey(t *testing.T) {
+	in := NewInput()
+	// Ctrl+A, then a plain W, in the same frame
+	in.Parse([]byte("\x01w"))
+	if !(input.Binding{Kind: input.KeyBinding, Name: "A", Ctrl: true}).IsTriggered(in) {
+		t.Error("Ctrl+A is not triggered")
+	}
+	if !in.IsMenuUp() {
+		t.Error("W does not move up in the menus")
+	}
+	in.Actions = input.NewActionMap()
+	in.Actions.Bind(input.MenuUp, input.Key("ArrowUp"))
+	if in.IsMenuUp() {
+		t.Error("W moves up in the menus after binding it to ArrowUp only")
+	}
+}
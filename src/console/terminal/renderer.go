@@ -0,0 +1,148 @@
+// Package terminal runs the console in a real terminal: frames are written as
+// ANSI escape sequences and keys and mouse reports are read from the raw
+// terminal input.
+package terminal
+
+import (
+	"io"
+
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/ansi"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// Renderer is a console.Renderer writing the frames to a terminal. Only the
+// changed cells are written, as cursor moves followed by SGR color sequences
+// and the rune.
+//
+// Terminal cells are half-width cells: a square cell of the console takes two
+// terminal columns, the second one being blank unless half-width cells are
+// displayed there.
+type Renderer struct {
+	w          io.Writer
+	encoder    ansi.Encoder
+	screen     *console.Screen // content of all the frames rendered so far
+	size       geometry.Point  // terminal size in columns and rows
+	repaintAll bool
+	dirty      []geometry.Point
+	isDirty    []bool
+	buf        []byte
+	err        error
+}
+
+// NewRenderer returns a renderer writing to w with the given color mode. The
+// terminal is assumed to be just large enough for the grid until SetSize is
+// called.
+func NewRenderer(w io.Writer, config console.GridConfig, mode ansi.ColorMode) *Renderer {
+	return &Renderer{
+		w:          w,
+		encoder:    ansi.Encoder{Mode: mode},
+		screen:     console.NewScreen(geometry.Point{X: config.GridWidth, Y: config.GridHeight}),
+		size:       geometry.Point{X: 2 * config.GridWidth, Y: config.GridHeight},
+		isDirty:    make([]bool, config.GridWidth*config.GridHeight),
+		repaintAll: true,
+	}
+}
+
+// Start switches to the alternate screen, hides the cursor and enables SGR
+// mouse reports, including motion events.
+func (r *Renderer) Start() error {
+	r.repaintAll = true
+	return r.write([]byte("\x1b[?1049h" + ansi.HideCursor + "\x1b[?1003h\x1b[?1006h"))
+}
+
+// Stop restores the terminal state changed by Start.
+func (r *Renderer) Stop() error {
+	return r.write([]byte(ansi.ResetStyle + "\x1b[?1006l\x1b[?1003l" + ansi.ShowCursor + "\x1b[?1049l"))
+}
+
+// SetSize informs the renderer of the terminal size in columns and rows. Cells beyond
+// it are not written. A change of size triggers a full repaint on the next
+// Render or Repaint, as terminals don't reliably keep the content on resize.
+func (r *Renderer) SetSize(size geometry.Point) {
+	if size != r.size {
+		r.size = size
+		r.repaintAll = true
+	}
+}
+
+// Resize implements console.Resizer. The terminal is repainted on the next
+// Render.
+func (r *Renderer) Resize(size geometry.Point) {
+	r.screen.Resize(size)
+	r.dirty = r.dirty[:0]
+	r.isDirty = make([]bool, size.X*size.Y)
+	r.repaintAll = true
+}
+
+// Render implements console.Renderer.
+func (r *Renderer) Render(frame console.Frame) {
+	if frame.IsFullRedraw || r.repaintAll {
+		r.screen.Apply(frame, nil)
+		r.Repaint()
+		return
+	}
+	r.screen.Apply(frame, r.markDirty)
+	r.buf = r.buf[:0]
+	w := r.screen.Size().X
+	for _, p := range r.dirty {
+		r.buf = r.appendSquare(r.buf, p)
+		r.isDirty[p.Y*w+p.X] = false
+	}
+	r.dirty = r.dirty[:0]
+	r.write(r.buf)
+}
+
+func (r *Renderer) markDirty(p geometry.Point) {
+	if !r.screen.Square.Contains(p) {
+		return
+	}
+	i := p.Y*r.screen.Size().X + p.X
+	if !r.isDirty[i] {
+		r.isDirty[i] = true
+		r.dirty = append(r.dirty, p)
+	}
+}
+
+// appendSquare appends the two terminal cells displaying a square cell.
+func (r *Renderer) appendSquare(buf []byte, p geometry.Point) []byte {
+	left, right := r.screen.Halves(p)
+	q := console.SquareToHalfWidth(p)
+	visible := geometry.Rect{Max: r.size}
+	if q.In(visible) {
+		buf = r.encoder.AppendCell(buf, q, left)
+	}
+	if q = q.Shift(1, 0); q.In(visible) {
+		buf = r.encoder.AppendCell(buf, q, right)
+	}
+	return buf
+}
+
+// Repaint clears the terminal and writes all the cells again.
+func (r *Renderer) Repaint() {
+	r.encoder.Reset()
+	r.buf = append(r.buf[:0], ansi.ResetStyle+ansi.ClearScreen...)
+	r.screen.Square.Range().Iter(func(p geometry.Point) {
+		r.buf = r.appendSquare(r.buf, p)
+	})
+	r.repaintAll = false
+	r.write(r.buf)
+}
+
+// Err returns the first error encountered while writing to the terminal, if
+// any.
+func (r *Renderer) Err() error {
+	return r.err
+}
+
+// write writes b and keeps the error if it is the first one.
+func (r *Renderer) write(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	_, err := r.w.Write(b)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return err
+}
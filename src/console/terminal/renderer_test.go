@@ -0,0 +1,63 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/ansi"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// failingWriter fails every write with an error numbered from 1.
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	w.writes++
+	return 0, fmt.Errorf("write %d failed", w.writes)
+}
+
+func TestRendererKeepsFirstError(t *testing.T) {
+	w := &failingWriter{}
+	config := console.GridConfig{GridWidth: 4, GridHeight: 2}
+	r := NewRenderer(w, config, ansi.TrueColor)
+	con := console.NewConsole(config, r)
+	for i := 0; i < 3; i++ {
+		con.Set(geometry.Point{X: i}, common.Cell{Char: 'x', Foreground: common.White, Background: common.Black})
+		con.Flush()
+	}
+	if w.writes < 2 {
+		t.Fatalf("%d writes, want at least 2", w.writes)
+	}
+	if err := r.Err(); err == nil || err.Error() != "write 1 failed" {
+		t.Errorf("Err() = %v, want the first error", err)
+	}
+}
+
+func TestRendererWritesChangedCells(t *testing.T) {
+	var buf bytes.Buffer
+	config := console.GridConfig{GridWidth: 4, GridHeight: 2}
+	r := NewRenderer(&buf, config, ansi.TrueColor)
+	con := console.NewConsole(config, r)
+	con.ClearScreen()
+	con.Flush()
+	buf.Reset()
+	con.Set(geometry.Point{X: 1, Y: 1}, common.Cell{Char: '@', Foreground: common.White, Background: common.Black})
+	con.Flush()
+	out := buf.String()
+	if strings.Contains(out, ansi.ClearScreen) {
+		t.Errorf("a delta frame cleared the screen: %q", out)
+	}
+	// the square cell (1,1) is displayed in the columns 3 and 4 of row 2
+	if !strings.Contains(out, "\x1b[2;3H") || !strings.Contains(out, "@") {
+		t.Errorf("output %q does not write '@' at row 2, column 3", out)
+	}
+	if err := r.Err(); err != nil {
+		t.Error(err)
+	}
+}
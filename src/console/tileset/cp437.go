@@ -0,0 +1,33 @@
+package tileset
+
+// cp437 lists the runes of code page 437, in code point order. The control
+// code points use their usual glyphs (smileys, card suits, arrows...).
+var cp437 = []rune("" +
+	"\u0000☺☻♥♦♣♠•◘○◙♂♀♪♫☼" +
+	"►◄↕‼¶§▬↨↑↓→←∟↔▲▼" +
+	" !\"#$%&'()*+,-./" +
+	"0123456789:;<=>?" +
+	"@ABCDEFGHIJKLMNO" +
+	"PQRSTUVWXYZ[\\]^_" +
+	"`abcdefghijklmno" +
+	"pqrstuvwxyz{|}~⌂" +
+	"ÇüéâäàåçêëèïîìÄÅ" +
+	"ÉæÆôöòûùÿÖÜ¢£¥₧ƒ" +
+	"áíóúñÑªº¿⌐¬½¼¡«»" +
+	"░▒▓│┤╡╢╖╕╣║╗╝╜╛┐" +
+	"└┴┬├─┼╞╟╚╔╩╦╠═╬╧" +
+	"╨╤╥╙╘╒╓╫╪┘┌█▄▌▐▀" +
+	"αßΓπΣσµτΦΘΩδ∞φε∩" +
+	"≡±≥≤⌠⌡÷≈°∙·√ⁿ²■ ")
+
+// CP437Mapping returns the mapping of the 256 tiles of a CP437 atlas, laid out
+// in 16 rows of 16 tiles, as in most roguelike tilesets.
+func CP437Mapping() map[rune]int {
+	mapping := make(map[rune]int, len(cp437))
+	for i, r := range cp437 {
+		if _, ok := mapping[r]; !ok {
+			mapping[r] = i
+		}
+	}
+	return mapping
+}
@@ -0,0 +1,135 @@
+// Package tileset loads bitmap tile atlases, for example CP437 fonts or
+// custom pixel tiles, to draw cells with tiles instead of TrueType glyphs.
+package tileset
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/fs"
+	"os"
+)
+
+// Tileset is an atlas of tiles of the same size, laid out in rows, with a
+// mapping from runes to tile indexes. Tile indexes go from left to right and
+// from top to bottom.
+//
+// Tiles are recolored when drawn: their colors are multiplied by the cell's
+// foreground color and they are drawn above the cell's background. Atlases
+// should thus be drawn in white over a transparent background. Fully opaque
+// monochrome atlases, like most CP437 images with white glyphs on black, are
+// converted on load: the luminance of each pixel becomes its opacity. Atlases
+// with colors are kept as they are, and their tiles are best drawn with a
+// white foreground.
+type Tileset struct {
+	Image      *image.NRGBA
+	TileWidth  int
+	TileHeight int
+	Columns    int
+	Mapping    map[rune]int
+}
+
+// Load decodes a PNG atlas from a file system, like an embed.FS or
+// os.DirFS. A nil mapping means CP437Mapping.
+func Load(fsys fs.FS, path string, tileWidth, tileHeight int, mapping map[rune]int) (*Tileset, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decoding tileset %s: %w", path, err)
+	}
+	return New(img, tileWidth, tileHeight, mapping)
+}
+
+// LoadFile is like Load for a file on disk.
+func LoadFile(path string, tileWidth, tileHeight int, mapping map[rune]int) (*Tileset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decoding tileset %s: %w", path, err)
+	}
+	return New(img, tileWidth, tileHeight, mapping)
+}
+
+// New returns a tileset using an already decoded atlas.
+func New(atlas image.Image, tileWidth, tileHeight int, mapping map[rune]int) (*Tileset, error) {
+	if tileWidth <= 0 || tileHeight <= 0 {
+		return nil, fmt.Errorf("invalid tile size %dx%d", tileWidth, tileHeight)
+	}
+	bounds := atlas.Bounds()
+	if bounds.Dx() < tileWidth || bounds.Dy() < tileHeight {
+		return nil, fmt.Errorf("atlas of size %dx%d is smaller than a tile", bounds.Dx(), bounds.Dy())
+	}
+	if mapping == nil {
+		mapping = CP437Mapping()
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(img, img.Bounds(), atlas, bounds.Min, draw.Src)
+	if isOpaque(img) && isGray(img) {
+		luminanceToAlpha(img)
+	}
+	return &Tileset{
+		Image:      img,
+		TileWidth:  tileWidth,
+		TileHeight: tileHeight,
+		Columns:    bounds.Dx() / tileWidth,
+		Mapping:    mapping,
+	}, nil
+}
+
+// TileCount returns the number of tiles in the atlas.
+func (ts *Tileset) TileCount() int {
+	return ts.Columns * (ts.Image.Bounds().Dy() / ts.TileHeight)
+}
+
+// Tile returns the index of the tile of a rune, and false if the rune has no
+// tile.
+func (ts *Tileset) Tile(r rune) (int, bool) {
+	index, ok := ts.Mapping[r]
+	if !ok || index < 0 || index >= ts.TileCount() {
+		return 0, false
+	}
+	return index, true
+}
+
+// TileBounds returns the bounds of a tile within the atlas.
+func (ts *Tileset) TileBounds(index int) image.Rectangle {
+	x := (index % ts.Columns) * ts.TileWidth
+	y := (index / ts.Columns) * ts.TileHeight
+	return image.Rect(x, y, x+ts.TileWidth, y+ts.TileHeight)
+}
+
+func isOpaque(img *image.NRGBA) bool {
+	for i := 3; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
+
+func isGray(img *image.NRGBA) bool {
+	for i := 0; i < len(img.Pix); i += 4 {
+		if img.Pix[i] != img.Pix[i+1] || img.Pix[i] != img.Pix[i+2] {
+			return false
+		}
+	}
+	return true
+}
+
+func luminanceToAlpha(img *image.NRGBA) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		c := color.NRGBA{R: img.Pix[i], G: img.Pix[i+1], B: img.Pix[i+2], A: 0xff}
+		y := color.GrayModel.Convert(c).(color.Gray).Y
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = 0xff, 0xff, 0xff, y
+	}
+}
@@ -0,0 +1,39 @@
+package tileset
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNewConvertsMonochromeAtlases(t *testing.T) {
+	tests := []struct {
+		name  string
+		pixel color.NRGBA // of every pixel of the atlas
+		want  color.NRGBA
+	}{
+		{"gray opaque atlas", color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff}, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x80}},
+		{"colored opaque atlas", color.NRGBA{R: 0xff, G: 0x80, A: 0xff}, color.NRGBA{R: 0xff, G: 0x80, A: 0xff}},
+		{"transparent atlas", color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x40}, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x40}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atlas := image.NewNRGBA(image.Rect(0, 0, 4, 2))
+			for y := 0; y < 2; y++ {
+				for x := 0; x < 4; x++ {
+					atlas.SetNRGBA(x, y, tt.pixel)
+				}
+			}
+			ts, err := New(atlas, 2, 2, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ts.Image.NRGBAAt(1, 1); got != tt.want {
+				t.Errorf("pixel = %v, want %v", got, tt.want)
+			}
+			if ts.TileCount() != 2 {
+				t.Errorf("TileCount() = %d, want 2", ts.TileCount())
+			}
+		})
+	}
+}
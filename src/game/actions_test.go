@@ -0,0 +1,33 @@
+package game_test
+
+import (
+	"testing"
+
+	"github.com/memmaker/ECon/console/headless"
+	"github.com/memmaker/ECon/game"
+	"github.com/memmaker/ECon/input"
+)
+
+func TestDefaultActions(t *testing.T) {
+	actions := game.DefaultActions()
+	// the menu and the map share the left click, the keys are free
+	for _, conflict := range actions.Conflicts() {
+		if conflict.Binding != input.Mouse(input.MouseLeft) {
+			t.Errorf("conflict between the default bindings: %s", conflict)
+		}
+	}
+	tests := []struct {
+		key    string
+		action input.Action
+	}{
+		{"C", game.ActionClear},
+		{"F12", game.ActionScreenshot},
+	}
+	for _, tt := range tests {
+		in := headless.NewInput()
+		in.PressKeys(tt.key)
+		if got := actions.Triggered(in); len(got) != 1 || got[0] != tt.action {
+			t.Errorf("actions triggered by %s = %v, want [%s]", tt.key, got, tt.action)
+		}
+	}
+}
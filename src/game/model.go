@@ -1,49 +1,103 @@
 package game
 
 import (
-	"github.com/hajimehoshi/ebiten/v2"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"math/rand"
 
 	"github.com/memmaker/ECon/common"
 	"github.com/memmaker/ECon/console"
 	"github.com/memmaker/ECon/geometry"
 	"github.com/memmaker/ECon/gridmap"
+	"github.com/memmaker/ECon/input"
 )
 
+// The actions of the model, bound by DefaultActions.
+const (
+	ActionClear     input.Action = "clear"
+	ActionPlaceWall input.Action = "place_wall"
+	// ActionScreenshot is handled by the engine, which saves the console
+	// to a PNG file.
+	ActionScreenshot input.Action = "screenshot"
+)
+
+// DefaultActions returns the default bindings of the model actions and of
+// the menu actions.
+func DefaultActions() *input.ActionMap {
+	actions := input.MenuActions()
+	actions.Bind(ActionClear, input.Key("C"))
+	actions.Bind(ActionPlaceWall, input.Mouse(input.MouseLeft))
+	actions.Bind(ActionScreenshot, input.Key("F12"))
+	return actions
+}
+
 type Model struct {
 	oldMousePos geometry.Point
 	playerPos   geometry.Point
 	config      console.GridConfig
 	gridMap     *gridmap.GridMap
+	camera      *gridmap.Camera
 	player      *gridmap.Actor
+	actions     *input.ActionMap
+	rng         *rand.Rand
 	clearScreen bool
 }
 
+// worldScale is the size of the map relative to the size of the grid.
+const worldScale = 2
+
 func NewModel(config console.GridConfig) *Model {
+	worldSize := geometry.Point{X: worldScale * config.GridWidth, Y: worldScale * config.GridHeight}
+	camera := gridmap.NewCamera(geometry.NewRect(0, 0, config.GridWidth, config.GridHeight), worldSize)
+	camera.Deadzone = geometry.Point{X: config.GridWidth / 4, Y: config.GridHeight / 4}
+	camera.Smoothing = 0.25
 	model := &Model{
 		config:  config,
-		gridMap: gridmap.NewMap(config.GridWidth, config.GridHeight),
+		gridMap: gridmap.NewMap(worldSize.X, worldSize.Y),
+		camera:  camera,
+		actions: DefaultActions(),
+		rng:     rand.New(rand.NewSource(1)),
 	}
 	return model
 }
 
+// SetActions replaces the bindings of the model actions, for example with
+// bindings loaded from a file.
+func (m *Model) SetActions(actions *input.ActionMap) {
+	m.actions = actions
+}
+
+// SetSeed seeds the random source of the model, which decides the walls
+// scattered by Init. It is called before Init. Input recordings store the
+// seed, so that a replay starts from the same map.
+func (m *Model) SetSeed(seed int64) {
+	m.rng.Seed(seed)
+}
+
+// Rand returns the random source of the model, seeded by SetSeed.
+func (m *Model) Rand() *rand.Rand {
+	return m.rng
+}
+
 // Update is called every frame
 func (m *Model) Update(engine console.Engine) {
 	userInput := engine.GetInput()
 
 	newMousePos := userInput.GetMousePos()
-	if newMousePos != m.oldMousePos {
-		m.gridMap.MoveActor(m.player, newMousePos)
+	worldPos, inView := m.camera.ScreenToWorld(newMousePos)
+	inView = inView && m.gridMap.Contains(worldPos)
+	if newMousePos != m.oldMousePos && inView {
+		m.gridMap.MoveActor(m.player, worldPos)
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyC) {
+	if m.actions.IsTriggered(userInput, ActionClear) {
 		m.clearScreen = true
 	}
-	if userInput.IsMouseLeft() {
-		m.PlaceWall(newMousePos)
-		//m.PlaceLight(newMousePos)
+	if m.actions.IsTriggered(userInput, ActionPlaceWall) && inView {
+		m.PlaceWall(worldPos)
+		//m.PlaceLight(worldPos)
 	}
 	m.oldMousePos = newMousePos
+	m.camera.Follow(m.player.Pos)
+	m.camera.Update()
 }
 
 // Draw is called every frame
@@ -56,10 +110,16 @@ func (m *Model) Draw(con console.CellInterface) {
 	m.drawMap(con)
 }
 
+// drawMap draws the part of the map seen by the camera. The cells of the
+// viewport beyond the map are blank.
 func (m *Model) drawMap(con console.CellInterface) {
-	m.gridMap.Iterate(func(p geometry.Point, cell gridmap.MapCell) {
-		drawCell := m.drawCell(p, cell)
-		con.Set(p, drawCell)
+	m.camera.Viewport.Iter(func(p geometry.Point) {
+		worldPos, _ := m.camera.ScreenToWorld(p)
+		if !m.gridMap.Contains(worldPos) {
+			con.Set(p, outsideCell)
+			return
+		}
+		con.Set(p, m.drawCell(worldPos, m.gridMap.GetCell(worldPos)))
 	})
 }
 
@@ -73,21 +133,60 @@ func (m *Model) drawCell(p geometry.Point, cell gridmap.MapCell) common.Cell {
 	return common.Cell{Char: drawRune, Foreground: cell.ForegroundColor, Background: cell.BackgroundColor}
 }
 
+const playerIcon = '@'
+
 var groundCell = gridmap.MapCell{Icon: '.', ForegroundColor: common.RGBColor{R: 0.8, G: 0.8, B: 0.8}, BackgroundColor: common.RGBColor{R: 97 / 255.0, G: 158 / 255.0, B: 1.0}}
 var wallCell = gridmap.MapCell{Icon: '#', IsOpaque: true, ForegroundColor: common.RGBColor{R: 0.8, G: 0.8, B: 0.8}, BackgroundColor: common.RGBColor{R: 0.9, G: 0.9, B: 0.9}}
 
+var outsideCell = common.Cell{Char: ' ', Foreground: common.White, Background: common.Black}
+
+// UsedRunes returns the runes the model draws, for font coverage checks.
+func UsedRunes() string {
+	return string([]rune{groundCell.Icon, wallCell.Icon, playerIcon})
+}
+
 func (m *Model) Init(engine console.Engine) {
 
 	m.gridMap.Fill(groundCell)
 	playerSpawn := geometry.Point{X: 10, Y: 10}
+	m.scatterWalls(playerSpawn)
 	m.player = &gridmap.Actor{
-		Icon: '@',
+		Icon: playerIcon,
 		Pos:  playerSpawn,
 	}
 	m.gridMap.AddActor(m.player)
+	m.camera.CenterOn(playerSpawn)
+	m.camera.Snap()
+}
+
+// wallDensity is the number of map cells per wall scattered by Init.
+const wallDensity = 40
+
+// scatterWalls places walls at random on the map, with the random source of
+// the model, so that the seed decides the map. The spawn cell stays free.
+func (m *Model) scatterWalls(spawn geometry.Point) {
+	size := m.gridMap.Size()
+	for i := 0; i < size.X*size.Y/wallDensity; i++ {
+		p := geometry.Point{X: m.rng.Intn(size.X), Y: m.rng.Intn(size.Y)}
+		if p != spawn {
+			m.gridMap.SetCell(p, wallCell)
+		}
+	}
 }
 
 func (m *Model) PlaceWall(pos geometry.Point) {
 	dest := pos.Add(geometry.Point{Y: 1})
+	if !m.gridMap.Contains(dest) {
+		return
+	}
 	m.gridMap.SetCell(dest, wallCell)
 }
+
+// OnResize implements console.ResizeListener: the camera shows as much of the
+// map as the grid can hold.
+func (m *Model) OnResize(size geometry.Point) {
+	m.config.GridWidth = size.X
+	m.config.GridHeight = size.Y
+	m.camera.SetViewport(geometry.NewRect(0, 0, size.X, size.Y))
+	m.camera.Deadzone = geometry.Point{X: size.X / 4, Y: size.Y / 4}
+}
@@ -0,0 +1,69 @@
+package geometry
+
+// AstarPath returns a shortest path from a position to another, including
+// both, using the A* algorithm. It returns nil if there is no path, or if one
+// of the positions is out of the range.
+//
+// The returned slice is cached for efficiency, so results will be invalidated
+// by future calls.
+func (pr *PathRange) AstarPath(ast Astar, from, to Point) []Point {
+	if !from.In(pr.Rg) || !to.In(pr.Rg) {
+		return nil
+	}
+	nm := pr.AstarNodes
+	nm.Idx++
+	pq := &pr.AstarQueue
+	pq.reset()
+	start, goal := pr.idx(from), pr.idx(to)
+	n := nm.get(start)
+	n.Rank = ast.Estimation(from, to)
+	n.Open = true
+	pq.push(nm, start)
+	for len(pq.Items) > 0 {
+		i := pq.pop(nm)
+		current := &nm.Nodes[i]
+		current.Open = false
+		current.Closed = true
+		if i == goal {
+			return pr.astarPath(i)
+		}
+		p := pr.point(i)
+		for _, q := range ast.Neighbors(p) {
+			if !q.In(pr.Rg) {
+				continue
+			}
+			cost := current.Cost + ast.Cost(p, q)
+			j := pr.idx(q)
+			nb := nm.get(j)
+			switch {
+			case nb.Open && cost < nb.Cost:
+				// a better path to a queued node
+				nb.Rank -= nb.Cost - cost
+				nb.Cost = cost
+				nb.Parent = i
+				pq.fix(nm, nb.HeapIdx)
+			case !nb.Open && !nb.Closed:
+				nb.Cost = cost
+				nb.Rank = cost + ast.Estimation(q, to)
+				nb.Parent = i
+				nb.Open = true
+				pq.push(nm, j)
+			}
+		}
+	}
+	return nil
+}
+
+// astarPath returns the path ending at the node of index i, following the
+// parents.
+func (pr *PathRange) astarPath(i int) []Point {
+	pr.PathCache = pr.PathCache[:0]
+	for ; i != -1; i = pr.AstarNodes.Nodes[i].Parent {
+		pr.PathCache = append(pr.PathCache, pr.point(i))
+	}
+	path := pr.PathCache
+	for i := range path[:len(path)/2] {
+		path[i], path[len(path)-i-1] = path[len(path)-i-1], path[i]
+	}
+	return path
+}
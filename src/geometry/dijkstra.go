@@ -0,0 +1,234 @@
+package geometry
+
+// DijkstraMap computes a multi-source Dijkstra map: the cost of the cheapest
+// path from the nearest source to every position reachable with a cost not
+// greater than maxCost. It returns a cached slice of the reached nodes, in
+// order of increasing cost. Values can also be consulted individually with
+// DijkstraMapAt.
+//
+// Typical uses are auto-explore, with the unexplored positions as sources,
+// and monsters fleeing or chasing the player along the gradient of the map.
+//
+// The returned slice is cached for efficiency, so results will be invalidated
+// by future calls.
+func (pr *PathRange) DijkstraMap(dij Dijkstra, sources []Point, maxCost int) []Node {
+	nm := pr.DijkstraNodes
+	nm.Idx++
+	pq := &pr.DijkstraQueue
+	pq.reset()
+	pr.DijkstraIter = pr.DijkstraIter[:0]
+	for _, p := range sources {
+		if !p.In(pr.Rg) {
+			continue
+		}
+		i := pr.idx(p)
+		n := nm.get(i)
+		if n.Open {
+			continue
+		}
+		n.Open = true
+		pq.push(nm, i)
+	}
+	for len(pq.Items) > 0 {
+		i := pq.pop(nm)
+		current := &nm.Nodes[i]
+		current.Open = false
+		current.Closed = true
+		p := pr.point(i)
+		pr.DijkstraIter = append(pr.DijkstraIter, Node{P: p, Cost: current.Cost})
+		for _, q := range dij.Neighbors(p) {
+			if !q.In(pr.Rg) {
+				continue
+			}
+			cost := current.Cost + dij.Cost(p, q)
+			if cost > maxCost {
+				continue
+			}
+			j := pr.idx(q)
+			nb := nm.get(j)
+			switch {
+			case nb.Open && cost < nb.Cost:
+				nb.Cost = cost
+				nb.Rank = cost
+				pq.fix(nm, nb.HeapIdx)
+			case !nb.Open && !nb.Closed:
+				nb.Cost = cost
+				nb.Rank = cost
+				nb.Open = true
+				pq.push(nm, j)
+			}
+		}
+	}
+	return pr.DijkstraIter
+}
+
+// DijkstraMapAt returns the cost of the path to a position in the last
+// DijkstraMap. It returns a false boolean if the position was not reached.
+func (pr *PathRange) DijkstraMapAt(p Point) (int, bool) {
+	if !p.In(pr.Rg) {
+		return 0, false
+	}
+	n, ok := pr.DijkstraNodes.at(pr.idx(p))
+	if !ok || !n.Closed {
+		return 0, false
+	}
+	return n.Cost, true
+}
+
+// bfNode is a node of a breadth first map. Nodes whose Idx isn't the BfIdx
+// of the PathRange are stale.
+type bfNode struct {
+	Cost int
+	Idx  int
+}
+
+// BreadthFirstMap computes a multi-source breadth first map: the number of
+// moves from the nearest source to every position reachable in at most
+// maxCost moves. It is a faster Dijkstra map for graphs whose moves all cost
+// 1. It returns a cached slice of the reached nodes, in order of increasing
+// cost. Values can also be consulted individually with BreadthFirstMapAt.
+//
+// The returned slice is cached for efficiency, so results will be invalidated
+// by future calls.
+func (pr *PathRange) BreadthFirstMap(nb Pather, sources []Point, maxCost int) []Node {
+	if len(pr.BfMap) < pr.len() {
+		pr.BfMap = make([]bfNode, pr.Capacity)
+	}
+	pr.BfIdx++
+	pr.BfQueue = pr.BfQueue[:0]
+	for _, p := range sources {
+		if !p.In(pr.Rg) {
+			continue
+		}
+		n := &pr.BfMap[pr.idx(p)]
+		if n.Idx == pr.BfIdx {
+			continue
+		}
+		*n = bfNode{Cost: 0, Idx: pr.BfIdx}
+		pr.BfQueue = append(pr.BfQueue, Node{P: p})
+	}
+	// the queue keeps the dequeued nodes, it is the returned map
+	for head := 0; head < len(pr.BfQueue); head++ {
+		current := pr.BfQueue[head]
+		if current.Cost >= maxCost {
+			continue
+		}
+		for _, q := range nb.Neighbors(current.P) {
+			if !q.In(pr.Rg) {
+				continue
+			}
+			n := &pr.BfMap[pr.idx(q)]
+			if n.Idx == pr.BfIdx {
+				continue
+			}
+			*n = bfNode{Cost: current.Cost + 1, Idx: pr.BfIdx}
+			pr.BfQueue = append(pr.BfQueue, Node{P: q, Cost: current.Cost + 1})
+		}
+	}
+	return pr.BfQueue
+}
+
+// BreadthFirstMapAt returns the number of moves to a position in the last
+// BreadthFirstMap. It returns a false boolean if the position was not
+// reached.
+func (pr *PathRange) BreadthFirstMapAt(p Point) (int, bool) {
+	if !p.In(pr.Rg) || pr.BfMap == nil {
+		return 0, false
+	}
+	n := pr.BfMap[pr.idx(p)]
+	if n.Idx != pr.BfIdx {
+		return 0, false
+	}
+	return n.Cost, true
+}
+
+// ComputeCC computes the connected component of a position: the positions
+// reachable from it. It returns a cached slice of the positions of the
+// component, p included. Only the positions of that component have a label
+// for CCAt afterwards.
+//
+// The connected components only make sense if the moves of the Pather are
+// symmetric.
+//
+// The returned slice is cached for efficiency, so results will be invalidated
+// by future calls.
+func (pr *PathRange) ComputeCC(nb Pather, p Point) []Point {
+	pr.resetCC()
+	pr.CCIter = pr.CCIter[:0]
+	if !p.In(pr.Rg) {
+		return nil
+	}
+	pr.CCIdx = 1
+	pr.labelCC(nb, pr.idx(p), true)
+	return pr.CCIter
+}
+
+// ComputeCCAll computes the connected components of all the positions of the
+// range, labelling them for CCAt. Impassable positions, without neighbors,
+// are components on their own.
+func (pr *PathRange) ComputeCCAll(nb Pather) {
+	pr.resetCC()
+	pr.CCIdx = 0
+	for i := range pr.CC {
+		if pr.CC[i] != 0 {
+			continue
+		}
+		pr.CCIdx++
+		pr.labelCC(nb, i, false)
+	}
+}
+
+// CCAt returns the label of the connected component of a position, as
+// computed by the last ComputeCCAll or ComputeCC. Two positions are connected
+// if their labels are equal. It returns a false boolean if the position has
+// no label.
+func (pr *PathRange) CCAt(p Point) (int, bool) {
+	if !p.In(pr.Rg) || len(pr.CC) == 0 {
+		return 0, false
+	}
+	label := pr.CC[pr.idx(p)]
+	if label == 0 {
+		return 0, false
+	}
+	return label - 1, true
+}
+
+func (pr *PathRange) resetCC() {
+	n := pr.len()
+	if cap(pr.CC) < n {
+		pr.CC = make([]int, n, pr.Capacity)
+		return
+	}
+	pr.CC = pr.CC[:n]
+	for i := range pr.CC {
+		pr.CC[i] = 0
+	}
+}
+
+// labelCC labels the component of the position of index start with CCIdx,
+// with a depth first flood fill, appending its positions to CCIter if
+// collect is true.
+func (pr *PathRange) labelCC(nb Pather, start int, collect bool) {
+	label := pr.CCIdx // 0 is no label
+	pr.CC[start] = label
+	pr.CCStack = append(pr.CCStack[:0], start)
+	for len(pr.CCStack) > 0 {
+		i := pr.CCStack[len(pr.CCStack)-1]
+		pr.CCStack = pr.CCStack[:len(pr.CCStack)-1]
+		p := pr.point(i)
+		if collect {
+			pr.CCIter = append(pr.CCIter, p)
+		}
+		for _, q := range nb.Neighbors(p) {
+			if !q.In(pr.Rg) {
+				continue
+			}
+			j := pr.idx(q)
+			if pr.CC[j] != 0 {
+				continue
+			}
+			pr.CC[j] = label
+			pr.CCStack = append(pr.CCStack, j)
+		}
+	}
+}
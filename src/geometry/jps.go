@@ -0,0 +1,226 @@
+package geometry
+
+// JPSPath returns a shortest path from a position to another, including
+// both, using the jump point search algorithm, in a grid where positions are
+// either passable or not, and the 8 moves to the adjacent positions cost 1.
+// It returns nil if there is no path, or if one of the positions is out of
+// the range or impassable. The path is appended to the given path slice,
+// which may be nil.
+//
+// It finds the same path lengths as AstarPath with a GridPather using
+// EightWay moves and unit costs, but is faster: instead of queuing every
+// position along a straight line, it jumps to the positions where the line
+// meets an obstacle, the jump points. The passable function is called once
+// per position of the range, so for short paths in a large range AstarPath
+// may be faster.
+//
+// It uses the same caches as AstarPath, so it invalidates its results.
+func (pr *PathRange) JPSPath(path []Point, from, to Point, passable func(Point) bool) []Point {
+	if !from.In(pr.Rg) || !to.In(pr.Rg) || !passable(from) || !passable(to) {
+		return nil
+	}
+	pr.fillJPSGrid(passable)
+	nm := pr.AstarNodes
+	nm.Idx++
+	pq := &pr.AstarQueue
+	pq.reset()
+	start, goal := pr.idx(from), pr.idx(to)
+	n := nm.get(start)
+	n.Rank = ChebyshevDistance(from, to)
+	n.Open = true
+	pq.push(nm, start)
+	w := pr.jpsWidth
+	jgoal := pr.jpsIdx(to)
+	for len(pq.Items) > 0 {
+		i := pq.pop(nm)
+		current := &nm.Nodes[i]
+		current.Open = false
+		current.Closed = true
+		if i == goal {
+			return pr.jpsPath(path, i)
+		}
+		p := pr.point(i)
+		ji := pr.jpsIdx(p)
+		var d Point
+		if current.Parent != -1 {
+			d = p.Sub(pr.point(current.Parent))
+			d = Point{X: sign(d.X), Y: sign(d.Y)}
+		}
+		dirs := pr.jpsDirections(ji, d)
+		for k := 0; k < 9; k++ {
+			if dirs&(1<<k) == 0 {
+				continue
+			}
+			dx, dy := k%3-1, k/3-1
+			jq, ok := pr.jump(ji, dx, dy, jgoal)
+			if !ok {
+				continue
+			}
+			// jumps follow a line: the distance is the number of steps
+			steps := (jq - ji) / (dx + dy*w)
+			q := p.Shift(steps*dx, steps*dy)
+			cost := current.Cost + steps
+			j := pr.idx(q)
+			nb := nm.get(j)
+			switch {
+			case nb.Open && cost < nb.Cost:
+				nb.Rank -= nb.Cost - cost
+				nb.Cost = cost
+				nb.Parent = i
+				pq.fix(nm, nb.HeapIdx)
+			case !nb.Open && !nb.Closed:
+				nb.Cost = cost
+				nb.Rank = cost + ChebyshevDistance(q, to)
+				nb.Parent = i
+				nb.Open = true
+				pq.push(nm, j)
+			}
+		}
+	}
+	return nil
+}
+
+// fillJPSGrid caches which positions of the range are passable, surrounded
+// by a border of impassable positions, so that the jumps read a slice
+// without range checks.
+func (pr *PathRange) fillJPSGrid(passable func(Point) bool) {
+	size := pr.Rg.Size()
+	w := size.X + 2
+	n := w * (size.Y + 2)
+	if cap(pr.jpsGrid) < n {
+		pr.jpsGrid = make([]bool, n)
+	}
+	grid := pr.jpsGrid[:n]
+	pr.jpsGrid, pr.jpsWidth = grid, w
+	for x := 0; x < w; x++ {
+		grid[x], grid[n-w+x] = false, false
+	}
+	for y := 0; y < size.Y; y++ {
+		row := grid[(y+1)*w : (y+2)*w]
+		row[0], row[w-1] = false, false
+		for x := 0; x < size.X; x++ {
+			row[x+1] = passable(Point{X: pr.Rg.Min.X + x, Y: pr.Rg.Min.Y + y})
+		}
+	}
+}
+
+// jpsIdx returns the index in jpsGrid of a position of the range.
+func (pr *PathRange) jpsIdx(p Point) int {
+	return (p.Y-pr.Rg.Min.Y+1)*pr.jpsWidth + p.X - pr.Rg.Min.X + 1
+}
+
+// jpsDir returns the bit of a direction in the sets of jpsDirections.
+func jpsDir(dx, dy int) uint16 {
+	return 1 << ((dy+1)*3 + dx + 1)
+}
+
+// jpsDirections returns the set of directions worth exploring from the
+// position of grid index i, reached moving in direction d: the natural
+// directions, continuing the move, and the forced ones, around the obstacles
+// beside the move. From the start, with a zero d, all the directions are
+// explored.
+func (pr *PathRange) jpsDirections(i int, d Point) uint16 {
+	g, w := pr.jpsGrid, pr.jpsWidth
+	dx, dy := d.X, d.Y
+	switch {
+	case dx != 0 && dy != 0:
+		dirs := jpsDir(0, dy) | jpsDir(dx, 0) | jpsDir(dx, dy)
+		if !g[i-dx] {
+			dirs |= jpsDir(-dx, dy)
+		}
+		if !g[i-dy*w] {
+			dirs |= jpsDir(dx, -dy)
+		}
+		return dirs
+	case dx != 0:
+		dirs := jpsDir(dx, 0)
+		if !g[i+w] {
+			dirs |= jpsDir(dx, 1)
+		}
+		if !g[i-w] {
+			dirs |= jpsDir(dx, -1)
+		}
+		return dirs
+	case dy != 0:
+		dirs := jpsDir(0, dy)
+		if !g[i+1] {
+			dirs |= jpsDir(1, dy)
+		}
+		if !g[i-1] {
+			dirs |= jpsDir(-1, dy)
+		}
+		return dirs
+	}
+	return 0x1ff &^ jpsDir(0, 0)
+}
+
+// jump moves from the grid index i in direction (dx, dy) until it finds a
+// jump point: the goal, a position with a forced neighbor, or, for diagonal
+// moves, a position from which a straight jump finds a jump point. It
+// returns false if it hits an obstacle first.
+func (pr *PathRange) jump(i, dx, dy, goal int) (int, bool) {
+	if dx == 0 || dy == 0 {
+		return pr.jumpStraight(i, dx, dy, goal)
+	}
+	g, w := pr.jpsGrid, pr.jpsWidth
+	step := dx + dy*w
+	for {
+		i += step
+		if !g[i] {
+			return i, false
+		}
+		if i == goal ||
+			g[i-dx+dy*w] && !g[i-dx] || g[i+dx-dy*w] && !g[i-dy*w] {
+			return i, true
+		}
+		if _, ok := pr.jumpStraight(i, dx, 0, goal); ok {
+			return i, true
+		}
+		if _, ok := pr.jumpStraight(i, 0, dy, goal); ok {
+			return i, true
+		}
+	}
+}
+
+// jumpStraight is jump for the horizontal and vertical directions.
+func (pr *PathRange) jumpStraight(i, dx, dy, goal int) (int, bool) {
+	g, w := pr.jpsGrid, pr.jpsWidth
+	// side is the step to one side of the move
+	step, side := dx, w
+	if dx == 0 {
+		step, side = dy*w, 1
+	}
+	for {
+		i += step
+		if !g[i] {
+			return i, false
+		}
+		if i == goal ||
+			g[i+step+side] && !g[i+side] || g[i+step-side] && !g[i-side] {
+			return i, true
+		}
+	}
+}
+
+// jpsPath appends to path the positions from the start to the jump point of
+// index i, filling the straight lines between the jump points.
+func (pr *PathRange) jpsPath(path []Point, i int) []Point {
+	start := len(path)
+	nodes := pr.AstarNodes.Nodes
+	p := pr.point(i)
+	path = append(path, p)
+	for parent := nodes[i].Parent; parent != -1; parent = nodes[parent].Parent {
+		q := pr.point(parent)
+		d := q.Sub(p)
+		d = Point{X: sign(d.X), Y: sign(d.Y)}
+		for p != q {
+			p = p.Add(d)
+			path = append(path, p)
+		}
+	}
+	reversed := path[start:]
+	for i := range reversed[:len(reversed)/2] {
+		reversed[i], reversed[len(reversed)-i-1] = reversed[len(reversed)-i-1], reversed[i]
+	}
+	return path
+}
@@ -0,0 +1,129 @@
+package geometry
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestJPSPath(t *testing.T) {
+	rng := rand.New(rand.NewSource(9))
+	for _, density := range []float64{0, 0.1, 0.3, 0.45} {
+		for k := 0; k < 50; k++ {
+			m := randomMap(rng, 40, 25, density)
+			gp := NewGridPather(m.passable, EightWay)
+			pr := NewPathRange(m.rg)
+			from, to := m.randomOpen(rng), m.randomOpen(rng)
+			want := len(pr.AstarPath(gp, from, to))
+			path := pr.JPSPath(nil, from, to, m.passable)
+			if len(path) != want {
+				t.Fatalf("density %v: JPSPath(%v, %v) has %d positions, AstarPath %d", density, from, to, len(path), want)
+			}
+			if path == nil {
+				continue
+			}
+			if path[0] != from || path[len(path)-1] != to {
+				t.Fatalf("JPSPath(%v, %v) = %v does not join them", from, to, path)
+			}
+			pathCost(t, gp, path)
+		}
+	}
+}
+
+func TestJPSPathSubRange(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+	m := randomMap(rng, 40, 25, 0.2)
+	rg := NewRect(5, 3, 30, 20)
+	gp := NewGridPather(m.passable, EightWay)
+	pr := NewPathRange(rg)
+	for k := 0; k < 50; k++ {
+		from, to := m.randomOpen(rng), m.randomOpen(rng)
+		want := len(pr.AstarPath(gp, from, to))
+		path := pr.JPSPath(nil, from, to, m.passable)
+		if len(path) != want {
+			t.Fatalf("JPSPath(%v, %v) has %d positions, AstarPath %d", from, to, len(path), want)
+		}
+		for _, p := range path {
+			if !p.In(rg) {
+				t.Fatalf("JPSPath(%v, %v) = %v leaves the range", from, to, path)
+			}
+		}
+	}
+}
+
+func TestJPSPathAppends(t *testing.T) {
+	m := randomMap(rand.New(rand.NewSource(10)), 10, 10, 0)
+	pr := NewPathRange(m.rg)
+	prefix := []Point{{X: -1, Y: -1}}
+	path := pr.JPSPath(prefix, Point{X: 0, Y: 0}, Point{X: 3, Y: 1}, m.passable)
+	if len(path) != 5 || path[0] != prefix[0] || path[1] != (Point{}) || path[4] != (Point{X: 3, Y: 1}) {
+		t.Errorf("JPSPath appended %v", path)
+	}
+}
+
+func TestJPSPathImpassable(t *testing.T) {
+	m := randomMap(rand.New(rand.NewSource(11)), 10, 10, 0)
+	m.open[0] = false
+	pr := NewPathRange(m.rg)
+	if path := pr.JPSPath(nil, Point{}, Point{X: 5, Y: 5}, m.passable); path != nil {
+		t.Errorf("JPSPath from an impassable position = %v, want nil", path)
+	}
+	if path := pr.JPSPath(nil, Point{X: 5, Y: 5}, Point{X: 10, Y: 5}, m.passable); path != nil {
+		t.Errorf("JPSPath to a position out of the range = %v, want nil", path)
+	}
+}
+
+// benchmarkMaps are the open and cluttered maps of the path benchmarks.
+var benchmarkMaps = []struct {
+	name    string
+	density float64
+}{
+	{"open", 0},
+	{"cluttered", 0.3},
+}
+
+// benchmarkPath runs a path query between far apart positions of a map.
+func benchmarkPath(b *testing.B, density float64, query func(pr *PathRange, m *testMap, from, to Point) []Point) {
+	rng := rand.New(rand.NewSource(12))
+	m := randomMap(rng, 80, 40, density)
+	from, to := Point{X: 1, Y: 1}, Point{X: 78, Y: 38}
+	m.open[m.index(from)], m.open[m.index(to)] = true, true
+	pr := NewPathRange(m.rg)
+	if query(pr, m, from, to) == nil {
+		b.Fatalf("no path from %v to %v", from, to)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		query(pr, m, from, to)
+	}
+}
+
+func BenchmarkJPSPath(b *testing.B) {
+	for _, bm := range benchmarkMaps {
+		b.Run(bm.name, func(b *testing.B) {
+			var path []Point
+			var passable func(Point) bool
+			benchmarkPath(b, bm.density, func(pr *PathRange, m *testMap, from, to Point) []Point {
+				if passable == nil {
+					passable = m.passable
+				}
+				path = pr.JPSPath(path[:0], from, to, passable)
+				return path
+			})
+		})
+	}
+}
+
+func BenchmarkAstarPath(b *testing.B) {
+	for _, bm := range benchmarkMaps {
+		b.Run(bm.name, func(b *testing.B) {
+			var gp *GridPather
+			benchmarkPath(b, bm.density, func(pr *PathRange, m *testMap, from, to Point) []Point {
+				if gp == nil {
+					gp = NewGridPather(m.passable, EightWay)
+				}
+				return pr.AstarPath(gp, from, to)
+			})
+		})
+	}
+}
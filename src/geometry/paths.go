@@ -0,0 +1,378 @@
+// This file implements the common structures of the path finding algorithms:
+// A*, Dijkstra maps, breadth first maps and connected components.
+
+package geometry
+
+import (
+	"bytes"
+	"encoding/gob"
+)
+
+// PathRange allows for efficient path finding within a range of positions,
+// such as the range occupied by the whole map, or a part of it. Like FOV, it
+// caches its structures, so that repeated queries don't allocate memory
+// anymore once the caches are grown.
+//
+// PathRange elements must be created with NewPathRange.
+//
+// PathRange implements the gob.Decoder and gob.Encoder interfaces for easy
+// serialization.
+type PathRange struct {
+	innerPathRange
+}
+
+type innerPathRange struct {
+	Rg            Rect
+	AstarNodes    *nodeMap
+	AstarQueue    priorityQueue
+	PathCache     []Point
+	DijkstraNodes *nodeMap
+	DijkstraQueue priorityQueue
+	DijkstraIter  []Node
+	BfMap         []bfNode
+	BfIdx         int // generation of the valid BfMap nodes
+	BfQueue       []Node
+	CC            []int // connected component labels, plus 1
+	CCStack       []int
+	CCIter        []Point
+	CCIdx         int // last label
+	Capacity      int
+	jpsGrid       []bool // passable positions of the last JPSPath, see fillJPSGrid
+	jpsWidth      int    // width of jpsGrid
+}
+
+// NewPathRange returns a new PathRange for positions in a given range.
+func NewPathRange(rg Rect) *PathRange {
+	pr := &PathRange{}
+	pr.Rg = rg
+	max := rg.Size()
+	pr.Capacity = max.X * max.Y
+	pr.AstarNodes = &nodeMap{Nodes: make([]node, pr.Capacity)}
+	pr.DijkstraNodes = &nodeMap{Nodes: make([]node, pr.Capacity)}
+	return pr
+}
+
+// SetRange updates the range used by the PathRange. If the size is smaller,
+// cached structures will be preserved, otherwise they will be reinitialized.
+// The results of the previous queries are invalidated.
+func (pr *PathRange) SetRange(rg Rect) {
+	max := rg.Size()
+	if max.X*max.Y > pr.Capacity {
+		*pr = *NewPathRange(rg)
+		return
+	}
+	pr.Rg = rg
+	pr.AstarNodes.Idx++
+	pr.DijkstraNodes.Idx++
+	pr.DijkstraIter = pr.DijkstraIter[:0]
+	pr.BfIdx++
+	pr.CC = pr.CC[:0]
+	pr.CCIdx = 0
+}
+
+// Range returns the current PathRange's range of positions.
+func (pr *PathRange) Range() Rect {
+	return pr.Rg
+}
+
+// GobDecode implements gob.GobDecoder.
+func (pr *PathRange) GobDecode(bs []byte) error {
+	r := bytes.NewReader(bs)
+	gd := gob.NewDecoder(r)
+	ipr := &innerPathRange{}
+	err := gd.Decode(ipr)
+	if err != nil {
+		return err
+	}
+	pr.innerPathRange = *ipr
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder.
+func (pr *PathRange) GobEncode() ([]byte, error) {
+	buf := bytes.Buffer{}
+	ge := gob.NewEncoder(&buf)
+	err := ge.Encode(&pr.innerPathRange)
+	return buf.Bytes(), err
+}
+
+func (pr *PathRange) idx(p Point) int {
+	p = p.Sub(pr.Rg.Min)
+	w := pr.Rg.Max.X - pr.Rg.Min.X
+	return p.Y*w + p.X
+}
+
+func (pr *PathRange) point(i int) Point {
+	w := pr.Rg.Max.X - pr.Rg.Min.X
+	return Point{X: pr.Rg.Min.X + i%w, Y: pr.Rg.Min.Y + i/w}
+}
+
+func (pr *PathRange) len() int {
+	max := pr.Rg.Size()
+	return max.X * max.Y
+}
+
+// Node represents a position in a Dijkstra or breadth first map, with the
+// cost of the path to it from the nearest source.
+type Node struct {
+	P    Point
+	Cost int
+}
+
+// Pather is the interface of the graphs of the breadth first maps and the
+// connected components.
+type Pather interface {
+	// Neighbors returns the positions reachable in one move from a given
+	// position. Positions outside the PathRange's range are ignored.
+	// Impassable positions should have no neighbors, so that they don't
+	// join the connected components of their passable neighbors.
+	Neighbors(Point) []Point
+}
+
+// Dijkstra is the interface of the weighted graphs of the Dijkstra maps.
+type Dijkstra interface {
+	Pather
+
+	// Cost returns the cost of the move from a position to one of its
+	// neighbors. It should be positive.
+	Cost(from, to Point) int
+}
+
+// Astar is the interface of the graphs of the A* algorithm.
+type Astar interface {
+	Dijkstra
+
+	// Estimation returns a lower bound of the cost of a path between two
+	// positions, like a distance. An estimation greater than the real cost
+	// makes the search faster, but the paths found may not be the shortest
+	// anymore.
+	Estimation(from, to Point) int
+}
+
+// Neighbors fetches adjacent positions. It returns a cached slice for
+// efficiency, so results are invalidated by next method calls. It is
+// suitable for use in satisfying the Pather, Dijkstra and Astar interfaces.
+type Neighbors struct {
+	ps []Point
+}
+
+var cardinals = [4]Point{{X: 1}, {X: -1}, {Y: 1}, {Y: -1}}
+var diagonals = [4]Point{{X: 1, Y: -1}, {X: -1, Y: -1}, {X: 1, Y: 1}, {X: -1, Y: 1}}
+
+// All returns 8 adjacent positions, including diagonal ones, filtered by
+// keep function.
+func (nb *Neighbors) All(p Point, keep func(Point) bool) []Point {
+	nb.ps = nb.ps[:0]
+	nb.append(p, cardinals[:], keep)
+	nb.append(p, diagonals[:], keep)
+	return nb.ps
+}
+
+// Cardinal returns 4 adjacent cardinal positions, excluding diagonal ones,
+// filtered by keep function.
+func (nb *Neighbors) Cardinal(p Point, keep func(Point) bool) []Point {
+	nb.ps = nb.ps[:0]
+	nb.append(p, cardinals[:], keep)
+	return nb.ps
+}
+
+// Diagonal returns 4 adjacent diagonal (inter-cardinal) positions, filtered
+// by keep function.
+func (nb *Neighbors) Diagonal(p Point, keep func(Point) bool) []Point {
+	nb.ps = nb.ps[:0]
+	nb.append(p, diagonals[:], keep)
+	return nb.ps
+}
+
+func (nb *Neighbors) append(p Point, dirs []Point, keep func(Point) bool) {
+	for _, d := range dirs {
+		q := p.Add(d)
+		if keep(q) {
+			nb.ps = append(nb.ps, q)
+		}
+	}
+}
+
+// Moves are the moves allowed by a GridPather.
+type Moves int
+
+const (
+	FourWay  Moves = iota // cardinal moves only
+	EightWay              // cardinal and diagonal moves
+)
+
+// GridPather is a ready to use Astar for grids with impassable positions,
+// like walls, and uniform move costs.
+type GridPather struct {
+	Passable     func(Point) bool
+	Moves        Moves
+	Cost1        int // cost of a cardinal move, 1 if zero
+	DiagonalCost int // cost of a diagonal move, Cost1 if zero
+	nb           Neighbors
+}
+
+// NewGridPather returns a GridPather with unit costs.
+func NewGridPather(passable func(Point) bool, moves Moves) *GridPather {
+	return &GridPather{Passable: passable, Moves: moves}
+}
+
+// Neighbors implements Pather. Impassable positions have no neighbors.
+func (gp *GridPather) Neighbors(p Point) []Point {
+	if !gp.Passable(p) {
+		return nil
+	}
+	if gp.Moves == EightWay {
+		return gp.nb.All(p, gp.Passable)
+	}
+	return gp.nb.Cardinal(p, gp.Passable)
+}
+
+// Cost implements Dijkstra.
+func (gp *GridPather) Cost(from, to Point) int {
+	if from.X != to.X && from.Y != to.Y {
+		return gp.diagonalCost()
+	}
+	return gp.cardinalCost()
+}
+
+// Estimation implements Astar: the Manhattan distance for 4-way moves, the
+// octile distance, weighted by the costs, for 8-way moves. It never
+// overestimates, as A* requires to find the shortest paths.
+func (gp *GridPather) Estimation(from, to Point) int {
+	d := to.Sub(from)
+	dx, dy := abs(d.X), abs(d.Y)
+	c := gp.cardinalCost()
+	if gp.Moves == FourWay {
+		return c * (dx + dy)
+	}
+	diag := gp.diagonalCost()
+	if diag > 2*c {
+		// a diagonal move is never better than two cardinal ones
+		diag = 2 * c
+	}
+	straight := c
+	if diag < c {
+		// two diagonal moves may replace two cardinal ones
+		straight = diag
+	}
+	if dx > dy {
+		dx, dy = dy, dx
+	}
+	return diag*dx + straight*(dy-dx)
+}
+
+func (gp *GridPather) cardinalCost() int {
+	if gp.Cost1 <= 0 {
+		return 1
+	}
+	return gp.Cost1
+}
+
+func (gp *GridPather) diagonalCost() int {
+	if gp.DiagonalCost <= 0 {
+		return gp.cardinalCost()
+	}
+	return gp.DiagonalCost
+}
+
+// node is a cached node of the A* and Dijkstra algorithms. Nodes whose Idx
+// isn't the Idx of their nodeMap are stale: they belong to a previous query.
+type node struct {
+	Cost    int
+	Rank    int // Cost plus the estimation for A*
+	Parent  int // index of the previous node in the path, -1 for none
+	Idx     int
+	Open    bool
+	Closed  bool
+	HeapIdx int
+}
+
+type nodeMap struct {
+	Nodes []node
+	Idx   int
+}
+
+// get returns the node at index i, reset if it was stale.
+func (nm *nodeMap) get(i int) *node {
+	n := &nm.Nodes[i]
+	if n.Idx != nm.Idx {
+		*n = node{Idx: nm.Idx, Parent: -1}
+	}
+	return n
+}
+
+// at returns the node at index i, and whether it belongs to the last query.
+func (nm *nodeMap) at(i int) (*node, bool) {
+	n := &nm.Nodes[i]
+	return n, n.Idx == nm.Idx
+}
+
+// priorityQueue is a binary min heap of node indices, ordered by rank.
+type priorityQueue struct {
+	Items []int
+}
+
+func (pq *priorityQueue) reset() {
+	pq.Items = pq.Items[:0]
+}
+
+func (pq *priorityQueue) push(nm *nodeMap, i int) {
+	nm.Nodes[i].HeapIdx = len(pq.Items)
+	pq.Items = append(pq.Items, i)
+	pq.up(nm, len(pq.Items)-1)
+}
+
+func (pq *priorityQueue) pop(nm *nodeMap) int {
+	last := len(pq.Items) - 1
+	pq.swap(nm, 0, last)
+	i := pq.Items[last]
+	pq.Items = pq.Items[:last]
+	pq.down(nm, 0)
+	return i
+}
+
+// fix restores the heap order after the rank of the node at heap index h
+// decreased.
+func (pq *priorityQueue) fix(nm *nodeMap, h int) {
+	pq.up(nm, h)
+}
+
+func (pq *priorityQueue) less(nm *nodeMap, a, b int) bool {
+	return nm.Nodes[pq.Items[a]].Rank < nm.Nodes[pq.Items[b]].Rank
+}
+
+func (pq *priorityQueue) swap(nm *nodeMap, a, b int) {
+	pq.Items[a], pq.Items[b] = pq.Items[b], pq.Items[a]
+	nm.Nodes[pq.Items[a]].HeapIdx = a
+	nm.Nodes[pq.Items[b]].HeapIdx = b
+}
+
+func (pq *priorityQueue) up(nm *nodeMap, h int) {
+	for h > 0 {
+		parent := (h - 1) / 2
+		if !pq.less(nm, h, parent) {
+			return
+		}
+		pq.swap(nm, h, parent)
+		h = parent
+	}
+}
+
+func (pq *priorityQueue) down(nm *nodeMap, h int) {
+	n := len(pq.Items)
+	for {
+		smallest := h
+		left, right := 2*h+1, 2*h+2
+		if left < n && pq.less(nm, left, smallest) {
+			smallest = left
+		}
+		if right < n && pq.less(nm, right, smallest) {
+			smallest = right
+		}
+		if smallest == h {
+			return
+		}
+		pq.swap(nm, h, smallest)
+		h = smallest
+	}
+}
@@ -0,0 +1,274 @@
+package geometry
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// testMap is a map of passable positions for the path finding tests.
+type testMap struct {
+	rg   Rect
+	open []bool
+}
+
+// randomMap returns a map of the given size whose positions are impassable
+// with the given probability.
+func randomMap(rng *rand.Rand, w, h int, density float64) *testMap {
+	m := &testMap{rg: NewRect(0, 0, w, h), open: make([]bool, w*h)}
+	for i := range m.open {
+		m.open[i] = rng.Float64() >= density
+	}
+	return m
+}
+
+func (m *testMap) passable(p Point) bool {
+	return p.In(m.rg) && m.open[m.index(p)]
+}
+
+// index returns the index of a position in open.
+func (m *testMap) index(p Point) int {
+	return p.Y*m.rg.Max.X + p.X
+}
+
+// randomOpen returns a random passable position.
+func (m *testMap) randomOpen(rng *rand.Rand) Point {
+	for {
+		p := Point{X: rng.Intn(m.rg.Max.X), Y: rng.Intn(m.rg.Max.Y)}
+		if m.passable(p) {
+			return p
+		}
+	}
+}
+
+// patherConfigs are the move and cost settings of the tests.
+var patherConfigs = []struct {
+	name            string
+	moves           Moves
+	cost1, diagCost int
+}{
+	{"4-way", FourWay, 1, 1},
+	{"8-way", EightWay, 1, 1},
+	{"8-way costly diagonals", EightWay, 2, 3},
+	{"8-way cheap diagonals", EightWay, 3, 2},
+	{"8-way very costly diagonals", EightWay, 2, 5},
+	{"8-way very cheap diagonals", EightWay, 5, 2},
+}
+
+func TestEstimationAdmissible(t *testing.T) {
+	for _, cfg := range patherConfigs {
+		t.Run(cfg.name, func(t *testing.T) {
+			m := randomMap(rand.New(rand.NewSource(1)), 12, 12, 0)
+			gp := &GridPather{Passable: m.passable, Moves: cfg.moves, Cost1: cfg.cost1, DiagonalCost: cfg.diagCost}
+			pr := NewPathRange(m.rg)
+			to := Point{X: 5, Y: 6}
+			for _, n := range pr.DijkstraMap(gp, []Point{to}, 1<<30) {
+				if e := gp.Estimation(n.P, to); e > n.Cost {
+					t.Fatalf("Estimation(%v, %v) = %d, more than the cost %d", n.P, to, e, n.Cost)
+				}
+			}
+		})
+	}
+}
+
+// pathCost returns the cost of a path, checking that its moves are valid.
+func pathCost(t *testing.T, gp *GridPather, path []Point) int {
+	t.Helper()
+	cost := 0
+	for i := 1; i < len(path); i++ {
+		d := path[i].Sub(path[i-1])
+		if abs(d.X) > 1 || abs(d.Y) > 1 || d == (Point{}) || gp.Moves == FourWay && d.X != 0 && d.Y != 0 {
+			t.Fatalf("invalid move from %v to %v", path[i-1], path[i])
+		}
+		if !gp.Passable(path[i]) {
+			t.Fatalf("path goes through the impassable %v", path[i])
+		}
+		cost += gp.Cost(path[i-1], path[i])
+	}
+	return cost
+}
+
+func TestAstarPath(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, cfg := range patherConfigs {
+		t.Run(cfg.name, func(t *testing.T) {
+			for k := 0; k < 20; k++ {
+				m := randomMap(rng, 30, 20, 0.3)
+				gp := &GridPather{Passable: m.passable, Moves: cfg.moves, Cost1: cfg.cost1, DiagonalCost: cfg.diagCost}
+				pr := NewPathRange(m.rg)
+				from, to := m.randomOpen(rng), m.randomOpen(rng)
+				pr.DijkstraMap(gp, []Point{from}, 1<<30)
+				want, reachable := pr.DijkstraMapAt(to)
+				path := pr.AstarPath(gp, from, to)
+				if !reachable {
+					if path != nil {
+						t.Fatalf("AstarPath(%v, %v) = %v for an unreachable position", from, to, path)
+					}
+					continue
+				}
+				if len(path) == 0 || path[0] != from || path[len(path)-1] != to {
+					t.Fatalf("AstarPath(%v, %v) = %v does not join them", from, to, path)
+				}
+				if cost := pathCost(t, gp, path); cost != want {
+					t.Fatalf("AstarPath(%v, %v) costs %d, the Dijkstra map %d", from, to, cost, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAstarPathOutOfRange(t *testing.T) {
+	m := randomMap(rand.New(rand.NewSource(3)), 5, 5, 0)
+	pr := NewPathRange(m.rg)
+	if path := pr.AstarPath(NewGridPather(m.passable, EightWay), Point{}, Point{X: 5, Y: 0}); path != nil {
+		t.Errorf("AstarPath to a position out of the range = %v, want nil", path)
+	}
+}
+
+func TestDijkstraMapMaxCost(t *testing.T) {
+	m := randomMap(rand.New(rand.NewSource(4)), 10, 10, 0)
+	pr := NewPathRange(m.rg)
+	gp := NewGridPather(m.passable, FourWay)
+	nodes := pr.DijkstraMap(gp, []Point{{X: 0, Y: 0}, {X: 9, Y: 9}}, 2)
+	// 6 positions within 2 moves of each corner
+	if len(nodes) != 12 {
+		t.Errorf("DijkstraMap reached %d positions, want 12", len(nodes))
+	}
+	for i := 1; i < len(nodes); i++ {
+		if nodes[i].Cost < nodes[i-1].Cost {
+			t.Fatalf("nodes are not sorted by cost: %v", nodes)
+		}
+	}
+	if _, ok := pr.DijkstraMapAt(Point{X: 5, Y: 5}); ok {
+		t.Error("DijkstraMapAt reports a position beyond maxCost")
+	}
+	if cost, ok := pr.DijkstraMapAt(Point{X: 8, Y: 8}); !ok || cost != 2 {
+		t.Errorf("DijkstraMapAt((8,8)) = %d, %v, want 2, true", cost, ok)
+	}
+}
+
+func TestBreadthFirstMap(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	for _, moves := range []Moves{FourWay, EightWay} {
+		m := randomMap(rng, 30, 20, 0.3)
+		gp := NewGridPather(m.passable, moves)
+		pr := NewPathRange(m.rg)
+		sources := []Point{m.randomOpen(rng), m.randomOpen(rng)}
+		for _, maxCost := range []int{5, 1 << 30} {
+			nodes := pr.BreadthFirstMap(gp, sources, maxCost)
+			pr.DijkstraMap(gp, sources, maxCost)
+			reached := 0
+			m.rg.Iter(func(p Point) {
+				want, wantOK := pr.DijkstraMapAt(p)
+				got, ok := pr.BreadthFirstMapAt(p)
+				if ok != wantOK || got != want {
+					t.Fatalf("BreadthFirstMapAt(%v) = %d, %v, the Dijkstra map %d, %v", p, got, ok, want, wantOK)
+				}
+				if ok {
+					reached++
+				}
+			})
+			if len(nodes) != reached {
+				t.Errorf("BreadthFirstMap returned %d nodes, %d positions reached", len(nodes), reached)
+			}
+		}
+	}
+}
+
+func TestConnectedComponents(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	m := randomMap(rng, 30, 20, 0.45)
+	gp := NewGridPather(m.passable, FourWay)
+	pr := NewPathRange(m.rg)
+	for k := 0; k < 10; k++ {
+		p := m.randomOpen(rng)
+		pr.BreadthFirstMap(gp, []Point{p}, 1<<30)
+		reachable := make(map[Point]bool)
+		m.rg.Iter(func(q Point) {
+			if _, ok := pr.BreadthFirstMapAt(q); ok {
+				reachable[q] = true
+			}
+		})
+		cc := pr.ComputeCC(gp, p)
+		if len(cc) != len(reachable) {
+			t.Fatalf("ComputeCC(%v) has %d positions, %d are reachable", p, len(cc), len(reachable))
+		}
+		for _, q := range cc {
+			if !reachable[q] {
+				t.Fatalf("ComputeCC(%v) has the unreachable %v", p, q)
+			}
+		}
+		label, _ := pr.CCAt(p)
+		m.rg.Iter(func(q Point) {
+			l, ok := pr.CCAt(q)
+			if ok != reachable[q] || ok && l != label {
+				t.Fatalf("CCAt(%v) = %d, %v after ComputeCC(%v)", q, l, ok, p)
+			}
+		})
+
+		pr.ComputeCCAll(gp)
+		label, _ = pr.CCAt(p)
+		m.rg.Iter(func(q Point) {
+			l, ok := pr.CCAt(q)
+			if !ok {
+				t.Fatalf("CCAt(%v) has no label after ComputeCCAll", q)
+			}
+			if (l == label) != reachable[q] {
+				t.Fatalf("CCAt(%v) = %d, CCAt(%v) = %d, reachable: %v", q, l, p, label, reachable[q])
+			}
+		})
+	}
+}
+
+func TestPathRangeAllocs(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	m := randomMap(rng, 40, 30, 0.3)
+	gp := NewGridPather(m.passable, EightWay)
+	pr := NewPathRange(m.rg)
+	from, to := m.randomOpen(rng), m.randomOpen(rng)
+	sources := []Point{from}
+	queries := map[string]func(){
+		"AstarPath":       func() { pr.AstarPath(gp, from, to) },
+		"DijkstraMap":     func() { pr.DijkstraMap(gp, sources, 1<<30) },
+		"BreadthFirstMap": func() { pr.BreadthFirstMap(gp, sources, 1<<30) },
+		"ComputeCC":       func() { pr.ComputeCC(gp, from) },
+		"ComputeCCAll":    func() { pr.ComputeCCAll(gp) },
+	}
+	for name, query := range queries {
+		query() // grows the caches
+		if allocs := testing.AllocsPerRun(10, query); allocs != 0 {
+			t.Errorf("%s allocates %v times once the caches are grown, want 0", name, allocs)
+		}
+	}
+}
+
+func benchmarkQuery(b *testing.B, query func(pr *PathRange, gp *GridPather, from, to Point)) {
+	rng := rand.New(rand.NewSource(8))
+	m := randomMap(rng, 80, 40, 0.25)
+	gp := NewGridPather(m.passable, EightWay)
+	pr := NewPathRange(m.rg)
+	from, to := m.randomOpen(rng), m.randomOpen(rng)
+	query(pr, gp, from, to)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		query(pr, gp, from, to)
+	}
+}
+
+func BenchmarkDijkstraMap(b *testing.B) {
+	benchmarkQuery(b, func(pr *PathRange, gp *GridPather, from, to Point) {
+		pr.DijkstraMap(gp, []Point{from}, 1<<30)
+	})
+}
+
+func BenchmarkBreadthFirstMap(b *testing.B) {
+	benchmarkQuery(b, func(pr *PathRange, gp *GridPather, from, to Point) {
+		pr.BreadthFirstMap(gp, []Point{from}, 1<<30)
+	})
+}
+
+func BenchmarkComputeCCAll(b *testing.B) {
+	benchmarkQuery(b, func(pr *PathRange, gp *GridPather, from, to Point) {
+		pr.ComputeCCAll(gp)
+	})
+}
@@ -2,15 +2,18 @@ module github.com/memmaker/ECon
 
 go 1.19
 
-require github.com/hajimehoshi/ebiten/v2 v2.5.0-alpha.12
+require (
+	github.com/hajimehoshi/ebiten/v2 v2.5.0-alpha.12
+	github.com/tinne26/etxt v0.0.8
+	golang.org/x/image v0.3.0
+	golang.org/x/term v0.4.0
+)
 
 require (
 	github.com/ebitengine/purego v0.2.0-alpha.0.20230107011038-a7c4d8fb43b1 // indirect
 	github.com/go-gl/glfw/v3.3/glfw v0.0.0-20221017161538-93cebf72946b // indirect
 	github.com/jezek/xgb v1.1.0 // indirect
-	github.com/tinne26/etxt v0.0.8 // indirect
 	golang.org/x/exp/shiny v0.0.0-20230127140709-cafedaf64729 // indirect
-	golang.org/x/image v0.3.0 // indirect
 	golang.org/x/mobile v0.0.0-20221110043201-43a038452099 // indirect
 	golang.org/x/sync v0.1.0 // indirect
 	golang.org/x/sys v0.4.0 // indirect
@@ -0,0 +1,132 @@
+package gridmap
+
+import (
+	"math"
+
+	"github.com/memmaker/ECon/geometry"
+)
+
+// Camera shows a part of a world, for example a GridMap larger than the
+// screen, in a region of the console: the world point Origin() is displayed at
+// Viewport.Min.
+//
+// The camera scrolls towards a target position. Follow moves the target so
+// that a point, usually the player, stays within a deadzone around the center
+// of the viewport. The target is clamped so that the view doesn't go past the
+// edges of the world, unless the world is smaller than the viewport, in which
+// case it is centered.
+type Camera struct {
+	Viewport  geometry.Rect  // console region displaying the world
+	WorldSize geometry.Point // size of the world
+	// Deadzone is the distance from the center of the viewport, on each
+	// axis, within which a followed point doesn't scroll the camera.
+	Deadzone geometry.Point
+	// Smoothing is the fraction of the remaining distance to the target
+	// scrolled by each Update, for smooth scrolling. Values <= 0 or >= 1
+	// scroll to the target at once.
+	Smoothing float64
+
+	x, y             float64 // current top-left world position
+	targetX, targetY float64
+}
+
+func NewCamera(viewport geometry.Rect, worldSize geometry.Point) *Camera {
+	return &Camera{Viewport: viewport, WorldSize: worldSize}
+}
+
+// Origin returns the world point displayed at the top-left of the viewport.
+func (c *Camera) Origin() geometry.Point {
+	return geometry.Point{X: int(math.Round(c.x)), Y: int(math.Round(c.y))}
+}
+
+// VisibleWorld returns the range of world points displayed in the viewport.
+// It may extend beyond the world when the world is smaller than the viewport.
+func (c *Camera) VisibleWorld() geometry.Rect {
+	return c.Viewport.Sub(c.Viewport.Min).Add(c.Origin())
+}
+
+// WorldToScreen returns the console position of a world point, and whether it
+// is inside the viewport.
+func (c *Camera) WorldToScreen(p geometry.Point) (geometry.Point, bool) {
+	q := p.Sub(c.Origin()).Add(c.Viewport.Min)
+	return q, q.In(c.Viewport)
+}
+
+// ScreenToWorld returns the world point displayed at a console position, for
+// example the mouse position, and whether the position is inside the
+// viewport.
+func (c *Camera) ScreenToWorld(p geometry.Point) (geometry.Point, bool) {
+	return p.Sub(c.Viewport.Min).Add(c.Origin()), p.In(c.Viewport)
+}
+
+// Follow moves the target of the camera so that p is within the deadzone.
+func (c *Camera) Follow(p geometry.Point) {
+	size := c.Viewport.Size()
+	c.targetX = follow(c.targetX, float64(p.X), float64(size.X), float64(c.Deadzone.X))
+	c.targetY = follow(c.targetY, float64(p.Y), float64(size.Y), float64(c.Deadzone.Y))
+	c.clampTarget()
+}
+
+func follow(origin, p, size, deadzone float64) float64 {
+	center := origin + math.Floor(size/2)
+	if p > center+deadzone {
+		return origin + p - (center + deadzone)
+	}
+	if p < center-deadzone {
+		return origin + p - (center - deadzone)
+	}
+	return origin
+}
+
+// CenterOn moves the target of the camera so that p is at the center of the
+// viewport, as far as the edges of the world allow.
+func (c *Camera) CenterOn(p geometry.Point) {
+	size := c.Viewport.Size()
+	c.targetX = float64(p.X - size.X/2)
+	c.targetY = float64(p.Y - size.Y/2)
+	c.clampTarget()
+}
+
+// SetViewport changes the console region of the camera, for example after the
+// console was resized. The target is clamped again.
+func (c *Camera) SetViewport(viewport geometry.Rect) {
+	c.Viewport = viewport
+	c.clampTarget()
+}
+
+// Update scrolls the camera towards its target. It is meant to be called once
+// per frame.
+func (c *Camera) Update() {
+	if c.Smoothing <= 0 || c.Smoothing >= 1 {
+		c.Snap()
+		return
+	}
+	c.x += (c.targetX - c.x) * c.Smoothing
+	c.y += (c.targetY - c.y) * c.Smoothing
+	if math.Abs(c.targetX-c.x) < 0.5 && math.Abs(c.targetY-c.y) < 0.5 {
+		c.Snap()
+	}
+}
+
+// Snap moves the camera to its target at once.
+func (c *Camera) Snap() {
+	c.x, c.y = c.targetX, c.targetY
+}
+
+// IsScrolling reports whether the camera has not reached its target yet.
+func (c *Camera) IsScrolling() bool {
+	return c.x != c.targetX || c.y != c.targetY
+}
+
+func (c *Camera) clampTarget() {
+	size := c.Viewport.Size()
+	c.targetX = clampAxis(c.targetX, float64(size.X), float64(c.WorldSize.X))
+	c.targetY = clampAxis(c.targetY, float64(size.Y), float64(c.WorldSize.Y))
+}
+
+func clampAxis(origin, size, world float64) float64 {
+	if world <= size {
+		return math.Floor((world - size) / 2)
+	}
+	return math.Max(0, math.Min(origin, world-size))
+}
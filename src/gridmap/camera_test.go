@@ -0,0 +1,109 @@
+package gridmap
+
+import (
+	"testing"
+
+	"github.com/memmaker/ECon/geometry"
+)
+
+func TestCameraClamping(t *testing.T) {
+	world := geometry.Point{X: 100, Y: 50}
+	tests := []struct {
+		name     string
+		viewport geometry.Rect
+		world    geometry.Point
+		center   geometry.Point
+		want     geometry.Point
+	}{
+		{"inside", geometry.NewRect(5, 2, 25, 12), world, geometry.Point{X: 50, Y: 25}, geometry.Point{X: 40, Y: 20}},
+		{"top-left edge", geometry.NewRect(5, 2, 25, 12), world, geometry.Point{X: 3, Y: 1}, geometry.Point{X: 0, Y: 0}},
+		{"bottom-right edge", geometry.NewRect(5, 2, 25, 12), world, geometry.Point{X: 99, Y: 49}, geometry.Point{X: 80, Y: 40}},
+		{"small world centered", geometry.NewRect(0, 0, 20, 10), geometry.Point{X: 10, Y: 4}, geometry.Point{X: 9, Y: 3}, geometry.Point{X: -5, Y: -3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewCamera(tt.viewport, tt.world)
+			c.CenterOn(tt.center)
+			c.Update()
+			if got := c.Origin(); got != tt.want {
+				t.Errorf("Origin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	c := NewCamera(geometry.NewRect(0, 0, 20, 10), world)
+	c.CenterOn(geometry.Point{X: 99, Y: 49})
+	c.SetViewport(geometry.NewRect(0, 0, 40, 20))
+	c.Update()
+	if got, want := c.Origin(), (geometry.Point{X: 60, Y: 30}); got != want {
+		t.Errorf("Origin() after a larger viewport = %v, want %v", got, want)
+	}
+}
+
+func TestCameraFollow(t *testing.T) {
+	c := NewCamera(geometry.NewRect(0, 0, 20, 10), geometry.Point{X: 100, Y: 50})
+	c.Deadzone = geometry.Point{X: 2, Y: 1}
+	steps := []struct {
+		p    geometry.Point
+		want geometry.Point
+	}{
+		{geometry.Point{X: 12, Y: 4}, geometry.Point{X: 0, Y: 0}},    // in the deadzone
+		{geometry.Point{X: 15, Y: 8}, geometry.Point{X: 3, Y: 2}},    // past its bottom-right
+		{geometry.Point{X: 14, Y: 7}, geometry.Point{X: 3, Y: 2}},    // back in it
+		{geometry.Point{X: 10, Y: 5}, geometry.Point{X: 2, Y: 1}},    // past its top-left
+		{geometry.Point{X: 0, Y: 0}, geometry.Point{X: 0, Y: 0}},     // clamped
+		{geometry.Point{X: 99, Y: 49}, geometry.Point{X: 80, Y: 40}}, // clamped
+	}
+	for _, st := range steps {
+		c.Follow(st.p)
+		c.Update()
+		if got := c.Origin(); got != st.want {
+			t.Errorf("Origin() following %v = %v, want %v", st.p, got, st.want)
+		}
+	}
+}
+
+func TestCameraSmoothScrolling(t *testing.T) {
+	c := NewCamera(geometry.NewRect(0, 0, 20, 10), geometry.Point{X: 100, Y: 50})
+	c.Smoothing = 0.5
+	c.CenterOn(geometry.Point{X: 50, Y: 15})
+	c.Update()
+	if got, want := c.Origin(), (geometry.Point{X: 20, Y: 5}); got != want || !c.IsScrolling() {
+		t.Fatalf("Origin() after an Update = %v, scrolling: %v, want %v, true", got, c.IsScrolling(), want)
+	}
+	updates := 1
+	for c.IsScrolling() && updates < 100 {
+		c.Update()
+		updates++
+	}
+	if got, want := c.Origin(), (geometry.Point{X: 40, Y: 10}); got != want {
+		t.Errorf("Origin() after scrolling = %v, want %v", got, want)
+	}
+	// 40 halved until less than 0.5 away
+	if updates != 7 {
+		t.Errorf("scrolling took %d updates, want 7", updates)
+	}
+}
+
+func TestCameraPositions(t *testing.T) {
+	c := NewCamera(geometry.NewRect(5, 2, 25, 12), geometry.Point{X: 100, Y: 50})
+	c.CenterOn(geometry.Point{X: 50, Y: 25})
+	c.Snap()
+	world := geometry.Point{X: 45, Y: 21}
+	screen := geometry.Point{X: 10, Y: 3}
+	if got, ok := c.WorldToScreen(world); got != screen || !ok {
+		t.Errorf("WorldToScreen(%v) = %v, %v, want %v, true", world, got, ok, screen)
+	}
+	if got, ok := c.ScreenToWorld(screen); got != world || !ok {
+		t.Errorf("ScreenToWorld(%v) = %v, %v, want %v, true", screen, got, ok, world)
+	}
+	if _, ok := c.WorldToScreen(geometry.Point{X: 39, Y: 21}); ok {
+		t.Error("WorldToScreen reports a point left of the view as visible")
+	}
+	if _, ok := c.ScreenToWorld(geometry.Point{X: 4, Y: 3}); ok {
+		t.Error("ScreenToWorld reports a position left of the viewport as inside")
+	}
+	if got, want := c.VisibleWorld(), geometry.NewRect(40, 20, 60, 30); got != want {
+		t.Errorf("VisibleWorld() = %v, want %v", got, want)
+	}
+}
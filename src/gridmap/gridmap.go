@@ -79,10 +79,16 @@ func (m *GridMap) Iterate(f func(p geometry.Point, cell MapCell)) {
 		}
 	}
 }
+
 func (m *GridMap) IsTransparent(p geometry.Point) bool {
 	return !m.GetCell(p).IsOpaque
 }
 
+// Size returns the width and height of the map.
+func (m *GridMap) Size() geometry.Point {
+	return geometry.Point{X: m.width, Y: m.height}
+}
+
 func (m *GridMap) Contains(dest geometry.Point) bool {
 	return dest.X >= 0 && dest.X < m.width && dest.Y >= 0 && dest.Y < m.height
 }
@@ -0,0 +1,436 @@
+package input
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Action is the name of something the player can do, like "confirm" or
+// "clear_map", bound to inputs by an ActionMap.
+type Action string
+
+// The menu actions, used by the IsMenu methods of the inputs.
+const (
+	MenuClose   Action = "menu_close"
+	MenuConfirm Action = "menu_confirm"
+	MenuDown    Action = "menu_down"
+	MenuUp      Action = "menu_up"
+)
+
+// BindingKind is the kind of input of a Binding.
+type BindingKind int
+
+const (
+	KeyBinding BindingKind = iota
+	MouseBinding
+	GamepadBinding
+)
+
+// Mouse button names of the mouse bindings.
+const (
+	MouseLeft  = "Left"
+	MouseRight = "Right"
+)
+
+// Binding is an input triggering an action: a key, with modifiers for a
+// chord like Ctrl+S, a mouse button or a gamepad button.
+//
+// Bindings are written as text in the configuration files: a key name as
+// returned by GridInput.GetJustPressedKeys, optionally preceded by "Shift+",
+// "Ctrl+" and "Alt+", "Mouse:Left", "Mouse:Right", or "Gamepad:" followed by
+// a gamepad button name, like "Gamepad:RightBottom".
+type Binding struct {
+	Kind  BindingKind
+	Name  string // key, mouse button or gamepad button name
+	Shift bool
+	Ctrl  bool
+	Alt   bool
+}
+
+// Key returns the binding of a key without modifiers.
+func Key(name string) Binding {
+	return Binding{Kind: KeyBinding, Name: name}
+}
+
+// Mouse returns the binding of a mouse button, MouseLeft or MouseRight.
+func Mouse(button string) Binding {
+	return Binding{Kind: MouseBinding, Name: button}
+}
+
+// Gamepad returns the binding of a gamepad button.
+func Gamepad(button string) Binding {
+	return Binding{Kind: GamepadBinding, Name: button}
+}
+
+// ParseBinding parses the text form of a binding.
+func ParseBinding(s string) (Binding, error) {
+	switch {
+	case strings.HasPrefix(s, "Mouse:"):
+		button := strings.TrimPrefix(s, "Mouse:")
+		if button != MouseLeft && button != MouseRight {
+			return Binding{}, fmt.Errorf("unknown mouse button in binding %q", s)
+		}
+		return Mouse(button), nil
+	case strings.HasPrefix(s, "Gamepad:"):
+		button := strings.TrimPrefix(s, "Gamepad:")
+		if button == "" {
+			return Binding{}, fmt.Errorf("missing gamepad button in binding %q", s)
+		}
+		return Gamepad(button), nil
+	}
+	var b Binding
+	rest := s
+	for {
+		switch {
+		case strings.HasPrefix(rest, "Shift+"):
+			b.Shift = true
+			rest = strings.TrimPrefix(rest, "Shift+")
+			continue
+		case strings.HasPrefix(rest, "Ctrl+"):
+			b.Ctrl = true
+			rest = strings.TrimPrefix(rest, "Ctrl+")
+			continue
+		case strings.HasPrefix(rest, "Alt+"):
+			b.Alt = true
+			rest = strings.TrimPrefix(rest, "Alt+")
+			continue
+		}
+		break
+	}
+	if rest == "" || strings.ContainsAny(rest, "+: ") {
+		return Binding{}, fmt.Errorf("invalid key in binding %q", s)
+	}
+	b.Name = rest
+	return b, nil
+}
+
+// String returns the text form of the binding.
+func (b Binding) String() string {
+	switch b.Kind {
+	case MouseBinding:
+		return "Mouse:" + b.Name
+	case GamepadBinding:
+		return "Gamepad:" + b.Name
+	}
+	s := b.Name
+	if b.Alt {
+		s = "Alt+" + s
+	}
+	if b.Ctrl {
+		s = "Ctrl+" + s
+	}
+	if b.Shift {
+		s = "Shift+" + s
+	}
+	return s
+}
+
+// IsTriggered reports whether the binding was just pressed in the frame. Keys
+// and mouse buttons only trigger with exactly their modifiers held. The
+// modifiers are those of the KeyDown or MouseDown events of the key or
+// button, so that a chord and a plain key pressed in the same frame are told
+// apart, or else those of the frame, when the input reports modifiers (see
+// ModifierInput).
+func (b Binding) IsTriggered(in GridInput) bool {
+	switch b.Kind {
+	case GamepadBinding:
+		return contains(in.GetJustPressedGamepadButtons(), b.Name)
+	case MouseBinding:
+		switch b.Name {
+		case MouseLeft:
+			return in.IsMouseLeft() && b.eventModifiersMatch(in, Event{Kind: MouseDown, Button: ButtonLeft})
+		case MouseRight:
+			return in.IsMouseRight() && b.eventModifiersMatch(in, Event{Kind: MouseDown, Button: ButtonRight})
+		}
+		return false
+	}
+	return contains(in.GetJustPressedKeys(), b.Name) && b.eventModifiersMatch(in, Event{Kind: KeyDown, Key: b.Name})
+}
+
+// eventModifiersMatch reports whether the events like ev, with the same kind,
+// key and button, include one with the modifiers of the binding. Without such
+// events, the modifiers of the frame are compared.
+func (b Binding) eventModifiersMatch(in GridInput, ev Event) bool {
+	found := false
+	for _, e := range in.GetEvents() {
+		if e.Kind != ev.Kind || e.Key != ev.Key || e.Button != ev.Button {
+			continue
+		}
+		if b.modifiersEqual(e.Mods) {
+			return true
+		}
+		found = true
+	}
+	return !found && b.modifiersMatch(in)
+}
+
+func (b Binding) modifiersEqual(mods Modifiers) bool {
+	return mods.Has(ModShift) == b.Shift && mods.Has(ModControl) == b.Ctrl && mods.Has(ModAlt) == b.Alt
+}
+
+func (b Binding) modifiersMatch(in GridInput) bool {
+	return IsShiftDown(in) == b.Shift && IsControlDown(in) == b.Ctrl && IsAltDown(in) == b.Alt
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ActionMap binds actions to inputs. Models ask it which actions were
+// triggered instead of checking keys, so that the player can rebind them.
+// It is saved and loaded as a JSON object mapping each action to the text
+// forms of its bindings:
+//
+//	{
+//	  "clear_map": ["C"],
+//	  "menu_confirm": ["Enter", "Mouse:Left", "Gamepad:RightBottom"],
+//	  "save": ["Ctrl+S"]
+//	}
+//
+// The zero value is an empty map.
+type ActionMap struct {
+	bindings map[Action][]Binding
+}
+
+func NewActionMap() *ActionMap {
+	return &ActionMap{bindings: make(map[Action][]Binding)}
+}
+
+// MenuActions returns an action map with the default bindings of the menu
+// actions: Escape or the right face button, Enter, a left click or the bottom
+// face button, and the down and up arrows or S and W. The gamepad directions
+// move in the menus too, with repeat, see GridInput.GetGamepadDirection.
+func MenuActions() *ActionMap {
+	m := NewActionMap()
+	m.Bind(MenuClose, Key("Escape"), Gamepad(GamepadRightRight))
+	m.Bind(MenuConfirm, Key("Enter"), Mouse(MouseLeft), Gamepad(GamepadRightBottom))
+	m.Bind(MenuDown, Key("ArrowDown"), Key("S"))
+	m.Bind(MenuUp, Key("ArrowUp"), Key("W"))
+	return m
+}
+
+// menuActions holds the default bindings used by IsMenuTriggered.
+var menuActions = MenuActions()
+
+// IsMenuTriggered reports whether a menu action was triggered, with the
+// bindings of actions, or with the default ones of MenuActions if actions is
+// nil. The inputs implement their IsMenu methods with it.
+func IsMenuTriggered(in GridInput, actions *ActionMap, action Action) bool {
+	if actions == nil {
+		actions = menuActions
+	}
+	return actions.IsTriggered(in, action)
+}
+
+// Bind adds bindings to an action. A binding already bound to the action is
+// not added twice.
+func (m *ActionMap) Bind(action Action, bindings ...Binding) {
+	if m.bindings == nil {
+		m.bindings = make(map[Action][]Binding)
+	}
+	for _, b := range bindings {
+		if !m.IsBound(action, b) {
+			m.bindings[action] = append(m.bindings[action], b)
+		}
+	}
+}
+
+// Rebind replaces the bindings of an action.
+func (m *ActionMap) Rebind(action Action, bindings ...Binding) {
+	delete(m.bindings, action)
+	m.Bind(action, bindings...)
+}
+
+// Unbind removes a binding from an action.
+func (m *ActionMap) Unbind(action Action, binding Binding) {
+	bindings := m.bindings[action]
+	for i, b := range bindings {
+		if b == binding {
+			m.bindings[action] = append(bindings[:i:i], bindings[i+1:]...)
+			return
+		}
+	}
+}
+
+// IsBound reports whether a binding is bound to an action.
+func (m *ActionMap) IsBound(action Action, binding Binding) bool {
+	for _, b := range m.bindings[action] {
+		if b == binding {
+			return true
+		}
+	}
+	return false
+}
+
+// Bindings returns the bindings of an action.
+func (m *ActionMap) Bindings(action Action) []Binding {
+	return m.bindings[action]
+}
+
+// Actions returns the actions with bindings, sorted by name.
+func (m *ActionMap) Actions() []Action {
+	actions := make([]Action, 0, len(m.bindings))
+	for action, bindings := range m.bindings {
+		if len(bindings) > 0 {
+			actions = append(actions, action)
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i] < actions[j]
+	})
+	return actions
+}
+
+// IsTriggered reports whether one of the bindings of an action was just
+// pressed in the frame.
+func (m *ActionMap) IsTriggered(in GridInput, action Action) bool {
+	for _, b := range m.bindings[action] {
+		if b.IsTriggered(in) {
+			return true
+		}
+	}
+	return false
+}
+
+// Triggered returns the actions triggered in the frame, sorted by name.
+func (m *ActionMap) Triggered(in GridInput) []Action {
+	var actions []Action
+	for _, action := range m.Actions() {
+		if m.IsTriggered(in, action) {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// Merge adds the bindings of other, replacing the bindings of the actions it
+// has. It is used to apply a configuration file over the defaults.
+func (m *ActionMap) Merge(other *ActionMap) {
+	for action, bindings := range other.bindings {
+		m.Rebind(action, bindings...)
+	}
+}
+
+// Conflict is a binding bound to several actions.
+type Conflict struct {
+	Binding Binding
+	Actions []Action
+}
+
+func (c Conflict) String() string {
+	names := make([]string, len(c.Actions))
+	for i, action := range c.Actions {
+		names[i] = string(action)
+	}
+	return fmt.Sprintf("%s is bound to %s", c.Binding, strings.Join(names, ", "))
+}
+
+// Conflicts returns the bindings bound to several actions, sorted by their
+// text form. Some conflicts are on purpose, like a key confirming menus and
+// doing something else in the game, so it is up to the caller to report them.
+func (m *ActionMap) Conflicts() []Conflict {
+	byBinding := make(map[Binding][]Action)
+	for _, action := range m.Actions() {
+		for _, b := range m.bindings[action] {
+			byBinding[b] = append(byBinding[b], action)
+		}
+	}
+	var conflicts []Conflict
+	for b, actions := range byBinding {
+		if len(actions) > 1 {
+			conflicts = append(conflicts, Conflict{Binding: b, Actions: actions})
+		}
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Binding.String() < conflicts[j].Binding.String()
+	})
+	return conflicts
+}
+
+// MarshalJSON implements json.Marshaler.
+func (m *ActionMap) MarshalJSON() ([]byte, error) {
+	doc := make(map[Action][]string, len(m.bindings))
+	for action, bindings := range m.bindings {
+		texts := make([]string, len(bindings))
+		for i, b := range bindings {
+			texts[i] = b.String()
+		}
+		doc[action] = texts
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It replaces all the bindings.
+func (m *ActionMap) UnmarshalJSON(data []byte) error {
+	var doc map[Action][]string
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	bindings := make(map[Action][]Binding, len(doc))
+	for action, texts := range doc {
+		for _, text := range texts {
+			b, err := ParseBinding(text)
+			if err != nil {
+				return fmt.Errorf("action %s: %w", action, err)
+			}
+			bindings[action] = append(bindings[action], b)
+		}
+	}
+	m.bindings = bindings
+	return nil
+}
+
+// Save writes the action map as indented JSON.
+func (m *ActionMap) Save(w io.Writer) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// Load reads an action map written by Save.
+func Load(r io.Reader) (*ActionMap, error) {
+	m := NewActionMap()
+	if err := json.NewDecoder(r).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// LoadFile reads an action map from a JSON file.
+func LoadFile(path string) (*ActionMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := Load(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// SaveFile writes an action map to a JSON file.
+func (m *ActionMap) SaveFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := m.Save(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
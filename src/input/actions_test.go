@@ -0,0 +1,83 @@
+package input_test
+
+import (
+	"testing"
+
+	"github.com/memmaker/ECon/console/headless"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/input"
+)
+
+func TestActionMapZeroValue(t *testing.T) {
+	var m input.ActionMap
+	m.Bind("save", input.Binding{Kind: input.KeyBinding, Name: "S", Ctrl: true})
+	if !m.IsBound("save", input.Binding{Kind: input.KeyBinding, Name: "S", Ctrl: true}) {
+		t.Error("the binding of a zero ActionMap was not kept")
+	}
+	var loaded input.ActionMap
+	if err := loaded.UnmarshalJSON([]byte(`{"save": ["Ctrl+S"]}`)); err != nil {
+		t.Fatal(err)
+	}
+	loaded.Bind("quit", input.Key("Q"))
+	if got := loaded.Actions(); len(got) != 2 {
+		t.Errorf("Actions() = %v, want [quit save]", got)
+	}
+}
+
+func TestIsMenuTriggeredDefaults(t *testing.T) {
+	in := headless.NewInput()
+	in.PressKeys("Escape")
+	if !in.IsMenuClose() {
+		t.Error("Escape does not close the menu with nil Actions")
+	}
+	in.EndFrame()
+	in.ClickLeft(geometry.Point{X: 1, Y: 1})
+	if !in.IsMenuConfirm() {
+		t.Error("a left click does not confirm with nil Actions")
+	}
+}
+
+func TestIsMenuTriggeredActions(t *testing.T) {
+	in := headless.NewInput()
+	in.Actions = input.MenuActions()
+	in.Actions.Rebind(input.MenuClose, input.Key("Q"))
+	in.PressKeys("Escape")
+	if in.IsMenuClose() {
+		t.Error("Escape closes the menu after rebinding it to Q")
+	}
+	in.EndFrame()
+	in.PressKeys("Q")
+	if !in.IsMenuClose() {
+		t.Error("Q does not close the menu")
+	}
+}
+
+func TestBindingModifiersPerKey(t *testing.T) {
+	save := input.Binding{Kind: input.KeyBinding, Name: "S", Ctrl: true}
+	down := input.Key("ArrowDown")
+	in := headless.NewInput()
+	// Ctrl+S and a plain arrow in the same frame
+	in.HoldControl(true)
+	in.PressKeys("S")
+	in.HoldControl(false)
+	in.PressKeys("ArrowDown")
+	if !save.IsTriggered(in) {
+		t.Error("Ctrl+S is not triggered")
+	}
+	if !down.IsTriggered(in) {
+		t.Error("ArrowDown is not triggered")
+	}
+	if input.Key("S").IsTriggered(in) {
+		t.Error("S without Control is triggered by Ctrl+S")
+	}
+
+	in.EndFrame()
+	in.HoldShift(true)
+	in.ClickLeft(geometry.Point{})
+	if input.Mouse(input.MouseLeft).IsTriggered(in) {
+		t.Error("a left click binding is triggered by Shift+click")
+	}
+	if !(input.Binding{Kind: input.MouseBinding, Name: input.MouseLeft, Shift: true}).IsTriggered(in) {
+		t.Error("Shift+click is not triggered")
+	}
+}
@@ -0,0 +1,289 @@
+package input
+
+import (
+	"fmt"
+
+	"github.com/memmaker/ECon/geometry"
+)
+
+// EventKind is the kind of an input Event.
+type EventKind int
+
+const (
+	KeyDown EventKind = iota
+	KeyUp
+	KeyRepeat // a held key repeating, after the key repeat delay
+	Char      // a typed character
+	MouseDown
+	MouseUp
+	MouseMove // the mouse moved to another cell
+	Wheel
+	DragStart // the mouse left the cell where a button was pressed
+	DragEnd   // the button of a drag was released, after its MouseUp
+	GamepadConnected
+	GamepadDisconnected
+	GamepadDown
+	GamepadUp
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case KeyDown:
+		return "KeyDown"
+	case KeyUp:
+		return "KeyUp"
+	case KeyRepeat:
+		return "KeyRepeat"
+	case Char:
+		return "Char"
+	case MouseDown:
+		return "MouseDown"
+	case MouseUp:
+		return "MouseUp"
+	case MouseMove:
+		return "MouseMove"
+	case Wheel:
+		return "Wheel"
+	case DragStart:
+		return "DragStart"
+	case DragEnd:
+		return "DragEnd"
+	case GamepadConnected:
+		return "GamepadConnected"
+	case GamepadDisconnected:
+		return "GamepadDisconnected"
+	case GamepadDown:
+		return "GamepadDown"
+	case GamepadUp:
+		return "GamepadUp"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// MouseButton is a mouse button of the mouse events.
+type MouseButton int
+
+const (
+	ButtonLeft MouseButton = iota
+	ButtonRight
+	ButtonMiddle
+	buttonCount
+)
+
+func (b MouseButton) String() string {
+	switch b {
+	case ButtonLeft:
+		return "Left"
+	case ButtonRight:
+		return "Right"
+	case ButtonMiddle:
+		return "Middle"
+	}
+	return fmt.Sprintf("MouseButton(%d)", int(b))
+}
+
+// Modifiers is a set of modifier keys held during an event.
+type Modifiers int
+
+const (
+	ModShift Modifiers = 1 << iota
+	ModControl
+	ModAlt
+)
+
+// Has reports whether all the modifiers of mods are held.
+func (m Modifiers) Has(mods Modifiers) bool {
+	return m&mods == mods
+}
+
+// Event is an input event. The events of a frame are returned in the order
+// the input read them by GridInput.GetEvents, so that a click followed by a
+// move, or the releases of the keys, can be handled in order.
+//
+// What is seen of the input between two frames depends on the backend. The
+// headless and terminal inputs queue an event for every action or report
+// they get, in order, so nothing is missed; terminals don't report the key
+// releases though. The ebiten input builds the events from the state of the
+// keys and buttons at each tick instead: a key pressed and released within a
+// tick is missed, and the events of a tick are ordered by kind, not by time.
+//
+// Every event carries the modifiers held and the mouse position, in square
+// and half-width cells, at the time of the event.
+type Event struct {
+	Kind          EventKind
+	Key           string      // KeyDown, KeyUp and KeyRepeat, named like GetJustPressedKeys
+	Char          rune        // Char
+	Button        MouseButton // MouseDown, MouseUp, DragStart and DragEnd
+	Wheel         int         // Wheel notches, positive when scrolling up
+	Gamepad       int         // gamepad of the Gamepad events
+	GamepadButton string      // GamepadDown and GamepadUp, see the Gamepad constants
+	Pos           geometry.Point
+	HalfWidthPos  geometry.Point
+	Mods          Modifiers
+}
+
+func (e Event) String() string {
+	switch e.Kind {
+	case KeyDown, KeyUp, KeyRepeat:
+		return fmt.Sprintf("%s %s", e.Kind, e.Key)
+	case Char:
+		return fmt.Sprintf("%s %q", e.Kind, e.Char)
+	case MouseDown, MouseUp, DragStart, DragEnd:
+		return fmt.Sprintf("%s %s %v", e.Kind, e.Button, e.Pos)
+	case Wheel:
+		return fmt.Sprintf("%s %d %v", e.Kind, e.Wheel, e.Pos)
+	case GamepadConnected, GamepadDisconnected:
+		return fmt.Sprintf("%s %d", e.Kind, e.Gamepad)
+	case GamepadDown, GamepadUp:
+		return fmt.Sprintf("%s %d %s", e.Kind, e.Gamepad, e.GamepadButton)
+	}
+	return fmt.Sprintf("%s %v", e.Kind, e.Pos)
+}
+
+// EventQueue collects the events of a frame for the inputs. It keeps track of
+// the held mouse buttons to produce the drag events, and stamps the events
+// with the current modifiers and mouse position.
+//
+// The inputs push the events as they read them, and Clear the queue at the
+// end of the frame. The zero value is an empty queue.
+type EventQueue struct {
+	events   []Event
+	pos      geometry.Point
+	halfPos  geometry.Point
+	mods     Modifiers
+	down     [buttonCount]bool
+	downPos  [buttonCount]geometry.Point
+	dragging [buttonCount]bool
+}
+
+// Events returns a copy of the queued events.
+func (q *EventQueue) Events() []Event {
+	events := make([]Event, len(q.events))
+	copy(events, q.events)
+	return events
+}
+
+// Clear empties the queue. The held buttons, modifiers and mouse position are
+// kept for the next frame.
+func (q *EventQueue) Clear() {
+	q.events = q.events[:0]
+}
+
+// SetModifiers sets the modifiers of the next events.
+func (q *EventQueue) SetModifiers(mods Modifiers) {
+	q.mods = mods
+}
+
+// Modifiers returns the modifiers of the next events.
+func (q *EventQueue) Modifiers() Modifiers {
+	return q.mods
+}
+
+// IsButtonDown reports whether a mouse button is held.
+func (q *EventQueue) IsButtonDown(b MouseButton) bool {
+	return q.down[b]
+}
+
+// IsDragging reports whether a mouse button is held and the mouse left the
+// cell where it was pressed.
+func (q *EventQueue) IsDragging(b MouseButton) bool {
+	return q.dragging[b]
+}
+
+func (q *EventQueue) push(e Event) {
+	e.Pos = q.pos
+	e.HalfWidthPos = q.halfPos
+	e.Mods = q.mods
+	q.events = append(q.events, e)
+}
+
+// KeyDown queues the press of a key.
+func (q *EventQueue) KeyDown(key string) {
+	q.push(Event{Kind: KeyDown, Key: key})
+}
+
+// KeyUp queues the release of a key.
+func (q *EventQueue) KeyUp(key string) {
+	q.push(Event{Kind: KeyUp, Key: key})
+}
+
+// KeyRepeat queues a repetition of a held key.
+func (q *EventQueue) KeyRepeat(key string) {
+	q.push(Event{Kind: KeyRepeat, Key: key})
+}
+
+// Char queues a typed character.
+func (q *EventQueue) Char(r rune) {
+	q.push(Event{Kind: Char, Char: r})
+}
+
+// Wheel queues a turn of the mouse wheel, positive when scrolling up. Zero
+// notches are ignored.
+func (q *EventQueue) Wheel(notches int) {
+	if notches != 0 {
+		q.push(Event{Kind: Wheel, Wheel: notches})
+	}
+}
+
+// MouseMove moves the mouse. It queues a MouseMove event if the half-width
+// cell under the mouse changed, preceded by a DragStart for the held buttons
+// leaving the cell where they were pressed.
+func (q *EventQueue) MouseMove(pos, halfWidthPos geometry.Point) {
+	if halfWidthPos == q.halfPos && pos == q.pos {
+		return
+	}
+	q.pos, q.halfPos = pos, halfWidthPos
+	for b := ButtonLeft; b < buttonCount; b++ {
+		if q.down[b] && !q.dragging[b] && pos != q.downPos[b] {
+			q.dragging[b] = true
+			q.push(Event{Kind: DragStart, Button: b})
+		}
+	}
+	q.push(Event{Kind: MouseMove})
+}
+
+// MouseDown presses a mouse button at the current mouse position. A button
+// already held is ignored.
+func (q *EventQueue) MouseDown(b MouseButton) {
+	if q.down[b] {
+		return
+	}
+	q.down[b] = true
+	q.downPos[b] = q.pos
+	q.push(Event{Kind: MouseDown, Button: b})
+}
+
+// MouseUp releases a mouse button, ending its drag. A button not held is
+// ignored.
+func (q *EventQueue) MouseUp(b MouseButton) {
+	if !q.down[b] {
+		return
+	}
+	q.down[b] = false
+	q.push(Event{Kind: MouseUp, Button: b})
+	if q.dragging[b] {
+		q.dragging[b] = false
+		q.push(Event{Kind: DragEnd, Button: b})
+	}
+}
+
+// GamepadConnected queues the connection of a gamepad.
+func (q *EventQueue) GamepadConnected(id int) {
+	q.push(Event{Kind: GamepadConnected, Gamepad: id})
+}
+
+// GamepadDisconnected queues the disconnection of a gamepad.
+func (q *EventQueue) GamepadDisconnected(id int) {
+	q.push(Event{Kind: GamepadDisconnected, Gamepad: id})
+}
+
+// GamepadDown queues the press of a button of a gamepad, see the Gamepad
+// constants for the names.
+func (q *EventQueue) GamepadDown(id int, button string) {
+	q.push(Event{Kind: GamepadDown, Gamepad: id, GamepadButton: button})
+}
+
+// GamepadUp queues the release of a button of a gamepad.
+func (q *EventQueue) GamepadUp(id int, button string) {
+	q.push(Event{Kind: GamepadUp, Gamepad: id, GamepadButton: button})
+}
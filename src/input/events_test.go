@@ -0,0 +1,112 @@
+package input_test
+
+import (
+	"testing"
+
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/input"
+)
+
+func TestEventQueueOrder(t *testing.T) {
+	at := func(x int) geometry.Point { return geometry.Point{X: x} }
+	tests := []struct {
+		name string
+		push func(q *input.EventQueue)
+		want string
+	}{
+		{"keys", func(q *input.EventQueue) {
+			q.KeyDown("A")
+			q.Char('a')
+			q.KeyRepeat("A")
+			q.KeyUp("A")
+		}, `KeyDown A, Char 'a', KeyRepeat A, KeyUp A`},
+		{"click", func(q *input.EventQueue) {
+			q.MouseMove(at(1), at(2))
+			q.MouseDown(input.ButtonLeft)
+			q.MouseUp(input.ButtonLeft)
+		}, "MouseMove (1,0), MouseDown Left (1,0), MouseUp Left (1,0)"},
+		{"same cell", func(q *input.EventQueue) {
+			q.MouseMove(at(0), at(0))
+			q.MouseMove(at(0), at(1))
+		}, "MouseMove (0,0)"},
+		{"drag", func(q *input.EventQueue) {
+			q.MouseDown(input.ButtonRight)
+			q.MouseMove(at(0), at(1)) // the other half of the same cell
+			q.MouseMove(at(1), at(2))
+			q.MouseMove(at(2), at(4))
+			q.MouseMove(at(0), at(0)) // back to the pressed cell
+			q.MouseUp(input.ButtonRight)
+		}, "MouseDown Right (0,0), MouseMove (0,0), DragStart Right (1,0), MouseMove (1,0), MouseMove (2,0), MouseMove (0,0), MouseUp Right (0,0), DragEnd Right (0,0)"},
+		{"two buttons", func(q *input.EventQueue) {
+			q.MouseDown(input.ButtonLeft)
+			q.MouseDown(input.ButtonRight)
+			q.MouseMove(at(1), at(2))
+			q.MouseUp(input.ButtonLeft)
+			q.MouseUp(input.ButtonRight)
+		}, "MouseDown Left (0,0), MouseDown Right (0,0), DragStart Left (1,0), DragStart Right (1,0), MouseMove (1,0), MouseUp Left (1,0), DragEnd Left (1,0), MouseUp Right (1,0), DragEnd Right (1,0)"},
+		{"held and released twice", func(q *input.EventQueue) {
+			q.MouseDown(input.ButtonLeft)
+			q.MouseDown(input.ButtonLeft)
+			q.MouseUp(input.ButtonLeft)
+			q.MouseUp(input.ButtonLeft)
+		}, "MouseDown Left (0,0), MouseUp Left (0,0)"},
+		{"wheel", func(q *input.EventQueue) {
+			q.Wheel(0)
+			q.Wheel(-2)
+		}, "Wheel -2 (0,0)"},
+		{"gamepads", func(q *input.EventQueue) {
+			q.GamepadConnected(1)
+			q.GamepadDown(1, input.GamepadRightBottom)
+			q.GamepadUp(1, input.GamepadRightBottom)
+			q.GamepadDisconnected(1)
+		}, "GamepadConnected 1, GamepadDown 1 RightBottom, GamepadUp 1 RightBottom, GamepadDisconnected 1"},
+	}
+	for _, tt := range tests {
+		q := &input.EventQueue{}
+		tt.push(q)
+		if got := eventStrings(q); got != tt.want {
+			t.Errorf("%s: events = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEventQueueAcrossFrames(t *testing.T) {
+	q := &input.EventQueue{}
+	q.SetModifiers(input.ModShift | input.ModControl)
+	q.MouseMove(geometry.Point{X: 1, Y: 1}, geometry.Point{X: 3, Y: 1})
+	q.MouseDown(input.ButtonLeft)
+	events := q.Events()
+	e := events[len(events)-1]
+	if !e.Mods.Has(input.ModShift|input.ModControl) || e.Mods.Has(input.ModAlt) {
+		t.Errorf("modifiers of the event = %v, want Shift and Control", e.Mods)
+	}
+	if e.Pos != (geometry.Point{X: 1, Y: 1}) || e.HalfWidthPos != (geometry.Point{X: 3, Y: 1}) {
+		t.Errorf("positions of the event = %v, %v, want (1,1), (3,1)", e.Pos, e.HalfWidthPos)
+	}
+	events[0].Kind = input.Wheel
+	if q.Events()[0].Kind != input.MouseMove {
+		t.Error("Events returns the queue itself, not a copy")
+	}
+
+	// the held buttons and the position are kept for the next frame
+	q.Clear()
+	q.SetModifiers(0)
+	if got := eventStrings(q); got != "" {
+		t.Fatalf("events after Clear = %s, want none", got)
+	}
+	if !q.IsButtonDown(input.ButtonLeft) || q.IsDragging(input.ButtonLeft) {
+		t.Fatal("the button is not held after Clear")
+	}
+	q.MouseMove(geometry.Point{X: 2, Y: 1}, geometry.Point{X: 4, Y: 1})
+	if !q.IsDragging(input.ButtonLeft) {
+		t.Error("the button is not dragging after a move to another cell")
+	}
+	q.Clear()
+	q.MouseUp(input.ButtonLeft)
+	if got, want := eventStrings(q), "MouseUp Left (2,1), DragEnd Left (2,1)"; got != want {
+		t.Errorf("events of the release = %s, want %s", got, want)
+	}
+	if q.IsButtonDown(input.ButtonLeft) || q.IsDragging(input.ButtonLeft) {
+		t.Error("the button is still held after MouseUp")
+	}
+}
@@ -0,0 +1,191 @@
+package input
+
+import (
+	"math"
+
+	"github.com/memmaker/ECon/geometry"
+)
+
+// Gamepad button names, following the standard layout of ebiten: the right
+// cluster holds the face buttons (A, B, X, Y on Xbox pads), the left cluster
+// the D-pad.
+const (
+	GamepadRightBottom      = "RightBottom"
+	GamepadRightRight       = "RightRight"
+	GamepadRightLeft        = "RightLeft"
+	GamepadRightTop         = "RightTop"
+	GamepadFrontTopLeft     = "FrontTopLeft"
+	GamepadFrontTopRight    = "FrontTopRight"
+	GamepadFrontBottomLeft  = "FrontBottomLeft"
+	GamepadFrontBottomRight = "FrontBottomRight"
+	GamepadCenterLeft       = "CenterLeft"
+	GamepadCenterRight      = "CenterRight"
+	GamepadLeftStick        = "LeftStick"
+	GamepadRightStick       = "RightStick"
+	GamepadLeftTop          = "LeftTop"
+	GamepadLeftBottom       = "LeftBottom"
+	GamepadLeftLeft         = "LeftLeft"
+	GamepadLeftRight        = "LeftRight"
+	GamepadCenterCenter     = "CenterCenter"
+)
+
+// The repeat timing of the gamepad directions, in frames.
+const (
+	DirectionRepeatDelay    = 15
+	DirectionRepeatInterval = 5
+	CursorRepeatDelay       = 8
+	CursorRepeatInterval    = 2
+)
+
+// StickDeadzone is the distance from the center under which a stick is
+// considered at rest.
+const StickDeadzone = 0.5
+
+// DPadDirection returns the direction of the D-pad buttons held.
+func DPadDirection(up, down, left, right bool) geometry.Point {
+	var dir geometry.Point
+	if up {
+		dir.Y--
+	}
+	if down {
+		dir.Y++
+	}
+	if left {
+		dir.X--
+	}
+	if right {
+		dir.X++
+	}
+	return dir
+}
+
+// StickDirection returns one of the 8 directions for the axis values of a
+// stick, y growing downwards, or the zero point when the stick is in the
+// deadzone.
+func StickDirection(x, y, deadzone float64) geometry.Point {
+	length := math.Hypot(x, y)
+	if length < deadzone {
+		return geometry.Point{}
+	}
+	// each direction covers a 45° sector: a component counts when its angle
+	// with the stick is under 67.5°
+	threshold := length * math.Sin(math.Pi/8)
+	var dir geometry.Point
+	if math.Abs(x) > threshold {
+		dir.X = sign(x)
+	}
+	if math.Abs(y) > threshold {
+		dir.Y = sign(y)
+	}
+	return dir
+}
+
+func sign(v float64) int {
+	if v < 0 {
+		return -1
+	}
+	return 1
+}
+
+// DirectionRepeater turns a held direction into steps, like the key repeat
+// of a keyboard: a step when the direction is pushed, then after Delay
+// frames a step every Interval frames.
+type DirectionRepeater struct {
+	Delay    int
+	Interval int
+	dir      geometry.Point
+	held     int // frames the direction is held
+}
+
+// Update is called every frame with the direction held. It returns the
+// direction in the frames with a step, and the zero point in the others.
+func (r *DirectionRepeater) Update(dir geometry.Point) geometry.Point {
+	if dir != r.dir {
+		r.dir = dir
+		r.held = 0
+		return dir
+	}
+	if dir == (geometry.Point{}) {
+		return dir
+	}
+	r.held++
+	if r.held < r.Delay || r.Interval <= 0 || (r.held-r.Delay)%r.Interval != 0 {
+		return geometry.Point{}
+	}
+	return dir
+}
+
+// VirtualCursor is a cursor moved across the cells with a gamepad stick. It
+// stands in for the mouse, so that the mouse driven tools remain usable with
+// a gamepad: the inputs report its position as the mouse position while it
+// is active. While it is inactive, the inputs keep it at the mouse position,
+// so that it starts from there.
+//
+// The clicks of the cursor go through Press and Release, so that the buttons
+// it holds are known: ReleaseAll releases them when the cursor gets inactive.
+type VirtualCursor struct {
+	Pos    geometry.Point
+	Active bool // the cursor moved since the last move of the mouse
+	repeat DirectionRepeater
+	held   [buttonCount]bool // buttons pressed by the cursor
+}
+
+func NewVirtualCursor() *VirtualCursor {
+	return &VirtualCursor{repeat: DirectionRepeater{Delay: CursorRepeatDelay, Interval: CursorRepeatInterval}}
+}
+
+// Update is called every frame with the direction of the stick. The cursor
+// moves one cell per step, staying inside bounds, and becomes active when it
+// moves. It returns whether the cursor moved.
+func (c *VirtualCursor) Update(dir geometry.Point, bounds geometry.Rect) bool {
+	step := c.repeat.Update(dir)
+	if step == (geometry.Point{}) || bounds.Empty() {
+		return false
+	}
+	c.Active = true
+	pos := c.Pos.Add(step)
+	pos.X = clamp(pos.X, bounds.Min.X, bounds.Max.X-1)
+	pos.Y = clamp(pos.Y, bounds.Min.Y, bounds.Max.Y-1)
+	moved := pos != c.Pos
+	c.Pos = pos
+	return moved
+}
+
+// Press queues the press of a mouse button by the cursor. A button already
+// held, by the cursor or the mouse, is ignored.
+func (c *VirtualCursor) Press(q *EventQueue, b MouseButton) {
+	if q.IsButtonDown(b) {
+		return
+	}
+	c.held[b] = true
+	q.MouseDown(b)
+}
+
+// Release queues the release of a mouse button pressed by the cursor. The
+// buttons pressed with the mouse are left alone.
+func (c *VirtualCursor) Release(q *EventQueue, b MouseButton) {
+	if !c.held[b] {
+		return
+	}
+	c.held[b] = false
+	q.MouseUp(b)
+}
+
+// ReleaseAll queues the release of the buttons still held by the cursor, for
+// example when it gets inactive: they would stay held in the queue otherwise,
+// and the next press of the same mouse button would be ignored.
+func (c *VirtualCursor) ReleaseAll(q *EventQueue) {
+	for b := ButtonLeft; b < buttonCount; b++ {
+		c.Release(q, b)
+	}
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,139 @@
+package input_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/input"
+)
+
+func TestStickDirection(t *testing.T) {
+	tests := []struct {
+		x, y float64
+		want geometry.Point
+	}{
+		{0, 0, geometry.Point{}},
+		{0.3, 0.3, geometry.Point{}}, // in the deadzone
+		{1, 0, geometry.Point{X: 1}},
+		{0, -1, geometry.Point{Y: -1}},
+		{-0.6, 0.1, geometry.Point{X: -1}},
+		{1, 0.3, geometry.Point{X: 1}},
+		{1, 0.5, geometry.Point{X: 1, Y: 1}},
+		{0.7, -0.7, geometry.Point{X: 1, Y: -1}},
+		{-0.2, 0.9, geometry.Point{Y: 1}},
+	}
+	for _, tt := range tests {
+		if got := input.StickDirection(tt.x, tt.y, input.StickDeadzone); got != tt.want {
+			t.Errorf("StickDirection(%v, %v) = %v, want %v", tt.x, tt.y, got, tt.want)
+		}
+	}
+}
+
+func TestDPadDirection(t *testing.T) {
+	tests := []struct {
+		up, down, left, right bool
+		want                  geometry.Point
+	}{
+		{false, false, false, false, geometry.Point{}},
+		{true, false, true, false, geometry.Point{X: -1, Y: -1}},
+		{false, true, false, true, geometry.Point{X: 1, Y: 1}},
+		{true, true, false, true, geometry.Point{X: 1}},
+	}
+	for _, tt := range tests {
+		if got := input.DPadDirection(tt.up, tt.down, tt.left, tt.right); got != tt.want {
+			t.Errorf("DPadDirection(%v, %v, %v, %v) = %v, want %v", tt.up, tt.down, tt.left, tt.right, got, tt.want)
+		}
+	}
+}
+
+func TestDirectionRepeater(t *testing.T) {
+	right := geometry.Point{X: 1}
+	down := geometry.Point{Y: 1}
+	none := geometry.Point{}
+	tests := []struct {
+		name     string
+		interval int
+		dirs     []geometry.Point
+		steps    string // the frames with a step
+	}{
+		{"repeat", 2, []geometry.Point{right, right, right, right, right, right, right}, "x..x.x."},
+		{"release", 2, []geometry.Point{right, right, none, right, right}, "x..x."},
+		{"change", 2, []geometry.Point{right, right, down, down, down, down}, "x.x..x"},
+		{"no interval", 0, []geometry.Point{right, right, right, right, right, right}, "x....."},
+	}
+	for _, tt := range tests {
+		r := input.DirectionRepeater{Delay: 3, Interval: tt.interval}
+		var b strings.Builder
+		for i, dir := range tt.dirs {
+			switch r.Update(dir) {
+			case none:
+				b.WriteByte('.')
+			case dir:
+				b.WriteByte('x')
+			default:
+				t.Fatalf("%s: frame %d stepped another direction than %v", tt.name, i, dir)
+			}
+		}
+		if got := b.String(); got != tt.steps {
+			t.Errorf("%s: steps = %q, want %q", tt.name, got, tt.steps)
+		}
+	}
+}
+
+func TestVirtualCursorUpdate(t *testing.T) {
+	c := input.NewVirtualCursor()
+	bounds := geometry.NewRect(0, 0, 3, 2)
+	if c.Update(geometry.Point{}, bounds) || c.Active {
+		t.Fatal("the cursor moved without a direction")
+	}
+	if !c.Update(geometry.Point{X: 1, Y: 1}, bounds) || !c.Active || c.Pos != (geometry.Point{X: 1, Y: 1}) {
+		t.Fatalf("cursor after a step = %v, active %v, want (1,1), true", c.Pos, c.Active)
+	}
+	if !c.Update(geometry.Point{X: 1}, bounds) || c.Pos != (geometry.Point{X: 2, Y: 1}) {
+		t.Fatalf("cursor after a second step = %v, want (2,1)", c.Pos)
+	}
+	c.Update(geometry.Point{}, bounds)
+	if c.Update(geometry.Point{X: 1}, bounds) || c.Pos != (geometry.Point{X: 2, Y: 1}) {
+		t.Errorf("cursor stepping past the edge = %v, want it kept at (2,1)", c.Pos)
+	}
+}
+
+// eventStrings returns the strings of the events of a queue.
+func eventStrings(q *input.EventQueue) string {
+	var s []string
+	for _, e := range q.Events() {
+		s = append(s, e.String())
+	}
+	return strings.Join(s, ", ")
+}
+
+func TestVirtualCursorButtons(t *testing.T) {
+	q := &input.EventQueue{}
+	c := input.NewVirtualCursor()
+	c.Press(q, input.ButtonLeft)
+	q.MouseMove(geometry.Point{X: 1}, geometry.Point{X: 2})
+	// the cursor gets inactive with its button held
+	c.ReleaseAll(q)
+	q.MouseDown(input.ButtonLeft)
+	want := "MouseDown Left (0,0), DragStart Left (1,0), MouseMove (1,0), MouseUp Left (1,0), DragEnd Left (1,0), MouseDown Left (1,0)"
+	if got := eventStrings(q); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+
+	// the buttons held by the mouse are not the cursor's
+	q.Clear()
+	c.Press(q, input.ButtonLeft)
+	c.Release(q, input.ButtonLeft)
+	c.ReleaseAll(q)
+	if got := eventStrings(q); got != "" || !q.IsButtonDown(input.ButtonLeft) {
+		t.Errorf("events = %s, button down %v, want none and the mouse button held", got, q.IsButtonDown(input.ButtonLeft))
+	}
+	q.MouseUp(input.ButtonLeft)
+	c.Press(q, input.ButtonRight)
+	c.Release(q, input.ButtonRight)
+	c.ReleaseAll(q)
+	if got, want := eventStrings(q), "MouseUp Left (1,0), MouseDown Right (1,0), MouseUp Right (1,0)"; got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
@@ -4,6 +4,9 @@ import "github.com/memmaker/ECon/geometry"
 
 type GridInput interface {
 	GetMousePos() geometry.Point
+	// GetHalfWidthMousePos returns the half-width cell under the mouse,
+	// see console.Console.HalfWidth.
+	GetHalfWidthMousePos() geometry.Point
 	HasMouseMoved() bool
 
 	IsMouseLeft() bool
@@ -15,4 +18,68 @@ type GridInput interface {
 	IsMenuUp() bool
 
 	GetJustPressedKeys() []string
+	// GetInputChars returns the characters typed since the last frame, as
+	// produced by the keyboard layout, for text entry.
+	GetInputChars() []rune
+	// GetEvents returns the input events of the frame, oldest first. The
+	// polled methods above are enough for most models, the events add the
+	// releases, key repeats, middle button and drags.
+	GetEvents() []Event
+
+	// GetJustPressedGamepadButtons returns the names of the gamepad buttons
+	// pressed in the frame on any connected gamepad, see the Gamepad
+	// constants.
+	GetJustPressedGamepadButtons() []string
+	// GetGamepadDirection returns the direction pushed with the D-pad or the
+	// left stick of the gamepads, in the frames where it is pushed and then
+	// repeated, like a held key, see DirectionRepeater. It is the zero point
+	// in the other frames.
+	GetGamepadDirection() geometry.Point
+}
+
+// ModifierInput is implemented by the inputs that know whether the modifier
+// keys are held, for example to extend a text selection with Shift and the
+// arrow keys, or for key chords like Ctrl+S.
+type ModifierInput interface {
+	IsShiftDown() bool
+	IsControlDown() bool
+	IsAltDown() bool
+}
+
+// WheelInput is implemented by the inputs that report the mouse wheel.
+type WheelInput interface {
+	// GetWheel returns the number of notches the wheel turned in the frame,
+	// positive when scrolling up (away from the user).
+	GetWheel() int
+}
+
+// GetWheel returns the wheel notches of the frame, or 0 if the input doesn't
+// report the wheel.
+func GetWheel(in GridInput) int {
+	w, ok := in.(WheelInput)
+	if !ok {
+		return 0
+	}
+	return w.GetWheel()
+}
+
+// IsShiftDown reports whether a Shift key is held, or false if the input
+// doesn't know.
+func IsShiftDown(in GridInput) bool {
+	m, ok := in.(ModifierInput)
+	return ok && m.IsShiftDown()
+}
+
+// IsControlDown reports whether a Control key is held, or false if the input
+// doesn't know.
+func IsControlDown(in GridInput) bool {
+	m, ok := in.(ModifierInput)
+	return ok && m.IsControlDown()
+}
+
+// IsAltDown reports whether an Alt key is held, or false if the input doesn't
+// know.
+func IsAltDown(in GridInput) bool {
+	m, ok := in.(ModifierInput)
+	return ok && m.IsAltDown()
 }
@@ -0,0 +1,261 @@
+package input
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"io"
+
+	"github.com/memmaker/ECon/geometry"
+)
+
+// FrameState is the input of one frame, as seen by a model through the
+// GridInput methods, including the optional ones.
+type FrameState struct {
+	MousePos          geometry.Point
+	HalfWidthMousePos geometry.Point
+	MouseMoved        bool
+	MouseLeft         bool
+	MouseRight        bool
+	MenuClose         bool
+	MenuConfirm       bool
+	MenuDown          bool
+	MenuUp            bool
+	Keys              []string
+	Chars             []rune
+	Events            []Event
+	Shift             bool
+	Control           bool
+	Alt               bool
+	Wheel             int
+	GamepadButtons    []string
+	GamepadDirection  geometry.Point
+}
+
+// Capture returns the input state of the current frame.
+func Capture(in GridInput) FrameState {
+	return FrameState{
+		MousePos:          in.GetMousePos(),
+		HalfWidthMousePos: in.GetHalfWidthMousePos(),
+		MouseMoved:        in.HasMouseMoved(),
+		MouseLeft:         in.IsMouseLeft(),
+		MouseRight:        in.IsMouseRight(),
+		MenuClose:         in.IsMenuClose(),
+		MenuConfirm:       in.IsMenuConfirm(),
+		MenuDown:          in.IsMenuDown(),
+		MenuUp:            in.IsMenuUp(),
+		Keys:              in.GetJustPressedKeys(),
+		Chars:             in.GetInputChars(),
+		Events:            in.GetEvents(),
+		Shift:             IsShiftDown(in),
+		Control:           IsControlDown(in),
+		Alt:               IsAltDown(in),
+		Wheel:             GetWheel(in),
+		GamepadButtons:    in.GetJustPressedGamepadButtons(),
+		GamepadDirection:  in.GetGamepadDirection(),
+	}
+}
+
+// RecordingHeader is the first value of an input recording file. The grid
+// size is the one of the recorded session: the mouse positions only make
+// sense on a grid of the same size.
+type RecordingHeader struct {
+	Seed   int64 // seed of the random source of the model
+	Width  int
+	Height int
+}
+
+// Recording is a decoded input recording file.
+type Recording struct {
+	RecordingHeader
+	Frames []FrameState
+}
+
+// Recorder writes the input of each frame to a gzip compressed gob stream,
+// for bug reports: replayed with a Replay, the frames drive a model through
+// the same session, provided the model is seeded with the recorded seed.
+//
+// Every frame is flushed to the writer, so that the recording of a session
+// that crashed before Close can still be read up to its last frame. The grid
+// size is fixed: a session whose grid is resized can't be recorded.
+type Recorder struct {
+	zw  *gzip.Writer
+	enc *gob.Encoder
+}
+
+// NewRecorder returns a recorder writing to w, starting with the header.
+func NewRecorder(w io.Writer, header RecordingHeader) (*Recorder, error) {
+	zw := gzip.NewWriter(w)
+	rec := &Recorder{zw: zw, enc: gob.NewEncoder(zw)}
+	if err := rec.enc.Encode(&header); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Record writes the input of the current frame and flushes it. It is called
+// once per frame, before the model's Update.
+func (r *Recorder) Record(in GridInput) error {
+	state := Capture(in)
+	if err := r.enc.Encode(&state); err != nil {
+		return err
+	}
+	return r.zw.Flush()
+}
+
+// Close flushes the compressed stream. It does not close the underlying
+// writer.
+func (r *Recorder) Close() error {
+	return r.zw.Close()
+}
+
+// ReadRecording decodes a whole recording written by a Recorder. A recording
+// cut before the end of the compressed stream, because the recorder was
+// never closed, is read up to its last complete frame.
+func ReadRecording(rd io.Reader) (*Recording, error) {
+	zr, err := gzip.NewReader(rd)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	dec := gob.NewDecoder(zr)
+	rec := &Recording{}
+	if err := dec.Decode(&rec.RecordingHeader); err != nil {
+		return nil, err
+	}
+	for {
+		var state FrameState
+		err := dec.Decode(&state)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rec.Frames = append(rec.Frames, state)
+	}
+	return rec, nil
+}
+
+// Replay is a GridInput playing back the frames of a recording. Next moves
+// to the next frame, and has to be called before each Update of the model:
+//
+//	replay := input.NewReplay(rec)
+//	for replay.Next() {
+//		model.Update(engine) // engine.GetInput() returns replay
+//		model.Draw(con)
+//		con.Flush()
+//	}
+//
+// Before the first call to Next and after the last frame, the input is idle.
+type Replay struct {
+	frames []FrameState
+	index  int
+	state  FrameState
+}
+
+func NewReplay(rec *Recording) *Replay {
+	return &Replay{frames: rec.Frames, index: -1}
+}
+
+// Next moves to the next frame. It returns false when the recording is over.
+func (r *Replay) Next() bool {
+	if r.index < len(r.frames) {
+		r.index++
+	}
+	if r.index == len(r.frames) {
+		// idle, at the last mouse position
+		r.state = FrameState{MousePos: r.state.MousePos, HalfWidthMousePos: r.state.HalfWidthMousePos}
+		return false
+	}
+	r.state = r.frames[r.index]
+	return true
+}
+
+// Frame returns the index of the current frame, -1 before the first call to
+// Next.
+func (r *Replay) Frame() int {
+	return r.index
+}
+
+// Done reports whether all the frames were played.
+func (r *Replay) Done() bool {
+	return r.index >= len(r.frames)
+}
+
+// Len returns the number of frames of the recording.
+func (r *Replay) Len() int {
+	return len(r.frames)
+}
+
+func (r *Replay) GetMousePos() geometry.Point {
+	return r.state.MousePos
+}
+
+func (r *Replay) GetHalfWidthMousePos() geometry.Point {
+	return r.state.HalfWidthMousePos
+}
+
+func (r *Replay) HasMouseMoved() bool {
+	return r.state.MouseMoved
+}
+
+func (r *Replay) IsMouseLeft() bool {
+	return r.state.MouseLeft
+}
+
+func (r *Replay) IsMouseRight() bool {
+	return r.state.MouseRight
+}
+
+func (r *Replay) IsMenuClose() bool {
+	return r.state.MenuClose
+}
+
+func (r *Replay) IsMenuConfirm() bool {
+	return r.state.MenuConfirm
+}
+
+func (r *Replay) IsMenuDown() bool {
+	return r.state.MenuDown
+}
+
+func (r *Replay) IsMenuUp() bool {
+	return r.state.MenuUp
+}
+
+func (r *Replay) GetJustPressedKeys() []string {
+	return append([]string(nil), r.state.Keys...)
+}
+
+func (r *Replay) GetInputChars() []rune {
+	return append([]rune(nil), r.state.Chars...)
+}
+
+func (r *Replay) GetEvents() []Event {
+	return append([]Event(nil), r.state.Events...)
+}
+
+func (r *Replay) IsShiftDown() bool {
+	return r.state.Shift
+}
+
+func (r *Replay) IsControlDown() bool {
+	return r.state.Control
+}
+
+func (r *Replay) IsAltDown() bool {
+	return r.state.Alt
+}
+
+func (r *Replay) GetWheel() int {
+	return r.state.Wheel
+}
+
+func (r *Replay) GetJustPressedGamepadButtons() []string {
+	return append([]string(nil), r.state.GamepadButtons...)
+}
+
+func (r *Replay) GetGamepadDirection() geometry.Point {
+	return r.state.GamepadDirection
+}
@@ -0,0 +1,281 @@
+// Package layout arranges panels, like a map view, a sidebar, a message log
+// and a status bar, by splitting a range into rows and columns of fixed,
+// percentage or flexible sizes.
+//
+//	lay := layout.New(layout.Columns("root", layout.Flex(1),
+//		layout.Rows("main", layout.Flex(1),
+//			layout.Panel("map", layout.Flex(1)),
+//			layout.Panel("log", layout.Fixed(5)),
+//		),
+//		layout.Panel("sidebar", layout.Percent(25).WithMin(16)),
+//	))
+//	panels := lay.Grids(con.Grid()) // recomputed when the grid size changes
+//	drawMap(panels["map"])
+package layout
+
+import (
+	"github.com/memmaker/ECon/geometry"
+)
+
+type sizeKind int
+
+const (
+	fixed sizeKind = iota
+	percent
+	flex
+)
+
+// Size is the length of a panel along the direction of its parent.
+type Size struct {
+	kind  sizeKind
+	value int
+	min   int
+	max   int // 0 for no maximum
+}
+
+// Fixed is a size of n cells.
+func Fixed(n int) Size {
+	return Size{kind: fixed, value: n}
+}
+
+// Percent is a size of p percent of the parent's length.
+func Percent(p int) Size {
+	return Size{kind: percent, value: p}
+}
+
+// Flex is a share of the length left by the fixed and percentage sizes,
+// proportional to weight.
+func Flex(weight int) Size {
+	return Size{kind: flex, value: weight}
+}
+
+// WithMin returns the size with a minimum length.
+func (s Size) WithMin(n int) Size {
+	s.min = n
+	return s
+}
+
+// WithMax returns the size with a maximum length.
+func (s Size) WithMax(n int) Size {
+	s.max = n
+	return s
+}
+
+func (s Size) clamp(n int) int {
+	if s.max > 0 && n > s.max {
+		n = s.max
+	}
+	if n < s.min {
+		n = s.min
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// Direction is how a node arranges its children.
+type Direction int
+
+const (
+	Vertical   Direction = iota // children are rows, from top to bottom
+	Horizontal                  // children are columns, from left to right
+)
+
+// Node is a panel, or a group of panels arranged in one direction.
+type Node struct {
+	Name      string
+	Size      Size // length along the direction of the parent
+	Direction Direction
+	Children  []*Node
+}
+
+// Panel returns a node without children.
+func Panel(name string, size Size) *Node {
+	return &Node{Name: name, Size: size}
+}
+
+// Rows returns a node whose children are rows.
+func Rows(name string, size Size, children ...*Node) *Node {
+	return &Node{Name: name, Size: size, Direction: Vertical, Children: children}
+}
+
+// Columns returns a node whose children are columns.
+func Columns(name string, size Size, children ...*Node) *Node {
+	return &Node{Name: name, Size: size, Direction: Horizontal, Children: children}
+}
+
+// Split splits a range in the given direction into consecutive ranges of the
+// given sizes. Fixed and percentage sizes are computed first, then the flexible
+// sizes share the rest, all within their min and max constraints. When the
+// sizes don't fit, the last ranges are cut or empty.
+func Split(rg geometry.Rect, dir Direction, sizes ...Size) []geometry.Rect {
+	length := rg.Size().Y
+	if dir == Horizontal {
+		length = rg.Size().X
+	}
+	lengths := solve(length, sizes)
+	rects := make([]geometry.Rect, len(sizes))
+	pos := 0
+	for i, n := range lengths {
+		start, end := min(pos, length), min(pos+n, length)
+		var r geometry.Rect
+		if dir == Horizontal {
+			r = rg.Columns(start, end)
+		} else {
+			r = rg.Lines(start, end)
+		}
+		if r.Empty() {
+			r = geometry.Rect{}
+		}
+		rects[i] = r
+		pos = end
+	}
+	return rects
+}
+
+// solve returns the lengths of the sizes for a total length. The lengths are
+// never negative, but their sum exceeds the total length when the fixed and
+// percentage sizes, or the minimums, don't fit.
+func solve(length int, sizes []Size) []int {
+	lengths := make([]int, len(sizes))
+	rest := length
+	var flexible []int
+	for i, s := range sizes {
+		switch s.kind {
+		case fixed:
+			lengths[i] = s.clamp(s.value)
+		case percent:
+			lengths[i] = s.clamp(length * s.value / 100)
+		case flex:
+			flexible = append(flexible, i)
+			continue
+		}
+		rest -= lengths[i]
+	}
+	// share the rest between the flexible sizes; those whose share breaks a
+	// constraint get their bound and the others share again
+	for len(flexible) > 0 {
+		weights := 0
+		for _, i := range flexible {
+			weights += max(sizes[i].value, 1)
+		}
+		var free []int
+		clamped := false
+		for _, i := range flexible {
+			share := 0
+			if rest > 0 {
+				share = rest * max(sizes[i].value, 1) / weights
+			}
+			if bounded := sizes[i].clamp(share); bounded != share {
+				lengths[i] = bounded
+				rest -= bounded
+				clamped = true
+				continue
+			}
+			free = append(free, i)
+		}
+		if clamped {
+			flexible = free
+			continue
+		}
+		// the fixed and percentage sizes may have taken more than the length
+		rest = max(rest, 0)
+		// distribute the cells left by the integer divisions to the first ones
+		given := 0
+		for _, i := range flexible {
+			lengths[i] = rest * max(sizes[i].value, 1) / weights
+			given += lengths[i]
+		}
+		for k := 0; given < rest; k++ {
+			lengths[flexible[k%len(flexible)]]++
+			given++
+		}
+		break
+	}
+	return lengths
+}
+
+// Layout computes the ranges of the panels of a tree of nodes.
+type Layout struct {
+	Root  *Node
+	bound geometry.Rect
+	rects map[string]geometry.Rect
+}
+
+func New(root *Node) *Layout {
+	return &Layout{Root: root}
+}
+
+// Compute computes the ranges of all the named nodes within rg, the root
+// taking the whole range. It returns them by name.
+func (l *Layout) Compute(rg geometry.Rect) map[string]geometry.Rect {
+	l.bound = rg
+	l.rects = make(map[string]geometry.Rect)
+	l.compute(l.Root, rg)
+	return l.rects
+}
+
+func (l *Layout) compute(n *Node, rg geometry.Rect) {
+	if n.Name != "" {
+		l.rects[n.Name] = rg
+	}
+	if len(n.Children) == 0 {
+		return
+	}
+	sizes := make([]Size, len(n.Children))
+	for i, child := range n.Children {
+		sizes[i] = child.Size
+	}
+	for i, r := range Split(rg, n.Direction, sizes...) {
+		l.compute(n.Children[i], r)
+	}
+}
+
+// Update computes the ranges for a grid of the given size, if it changed
+// since the last computation, and reports whether it did.
+func (l *Layout) Update(size geometry.Point) bool {
+	rg := geometry.NewRect(0, 0, size.X, size.Y)
+	if l.rects != nil && rg == l.bound {
+		return false
+	}
+	l.Compute(rg)
+	return true
+}
+
+// Rect returns the range of a named node, as of the last computation.
+func (l *Layout) Rect(name string) geometry.Rect {
+	return l.rects[name]
+}
+
+// Grids returns the slices of gd of all the named nodes, computing the ranges
+// again if the size of gd changed.
+func (l *Layout) Grids(gd geometry.Grid) map[string]geometry.Grid {
+	l.Update(gd.Size())
+	grids := make(map[string]geometry.Grid, len(l.rects))
+	for name, rg := range l.rects {
+		grids[name] = gd.Slice(rg)
+	}
+	return grids
+}
+
+// Grid returns the slice of gd of a named node, computing the ranges again if
+// the size of gd changed.
+func (l *Layout) Grid(gd geometry.Grid, name string) geometry.Grid {
+	l.Update(gd.Size())
+	return gd.Slice(l.rects[name])
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,79 @@
+package layout
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/memmaker/ECon/geometry"
+)
+
+func TestSolve(t *testing.T) {
+	tests := []struct {
+		name   string
+		length int
+		sizes  []Size
+		want   []int
+	}{
+		{"fixed", 10, []Size{Fixed(3), Fixed(4)}, []int{3, 4}},
+		{"percent", 20, []Size{Percent(25), Percent(50)}, []int{5, 10}},
+		{"flex rest", 10, []Size{Fixed(4), Flex(1)}, []int{4, 6}},
+		{"flex weights", 9, []Size{Flex(1), Flex(2)}, []int{3, 6}},
+		{"flex remainder to the first", 10, []Size{Flex(1), Flex(1), Flex(1)}, []int{4, 3, 3}},
+		{"zero weight counts as one", 4, []Size{Flex(0), Flex(1)}, []int{2, 2}},
+		{"flex max", 10, []Size{Flex(1).WithMax(2), Flex(1)}, []int{2, 8}},
+		{"flex min", 10, []Size{Flex(1).WithMin(8), Flex(1)}, []int{8, 2}},
+		{"percent min", 20, []Size{Percent(10).WithMin(5), Flex(1)}, []int{5, 15}},
+		{"fixed overflow", 10, []Size{Fixed(8), Flex(1), Fixed(4)}, []int{8, 0, 4}},
+		{"flex min overflow", 10, []Size{Fixed(8), Flex(1).WithMin(3), Flex(1)}, []int{8, 3, 0}},
+		{"empty length", 0, []Size{Flex(1), Fixed(2)}, []int{0, 2}},
+		{"negative fixed", 5, []Size{Fixed(-3), Flex(1)}, []int{0, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := solve(tt.length, tt.sizes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("solve(%d, %v) = %v, want %v", tt.length, tt.sizes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitOverflow(t *testing.T) {
+	rg := geometry.NewRect(0, 0, 10, 10)
+	got := Split(rg, Vertical, Fixed(8), Flex(1), Fixed(4))
+	want := []geometry.Rect{geometry.NewRect(0, 0, 10, 8), {}, geometry.NewRect(0, 8, 10, 10)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Split() = %v, want %v", got, want)
+	}
+	got = Split(rg, Horizontal, Fixed(6), Fixed(6), Fixed(6))
+	want = []geometry.Rect{geometry.NewRect(0, 0, 6, 10), geometry.NewRect(6, 0, 10, 10), {}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Split() = %v, want %v", got, want)
+	}
+}
+
+func TestLayoutCompute(t *testing.T) {
+	lay := New(Columns("root", Flex(1),
+		Rows("main", Flex(1),
+			Panel("map", Flex(1)),
+			Panel("log", Fixed(5)),
+		),
+		Panel("sidebar", Percent(25).WithMin(16)),
+	))
+	rects := lay.Compute(geometry.NewRect(0, 0, 80, 30))
+	want := map[string]geometry.Rect{
+		"root":    geometry.NewRect(0, 0, 80, 30),
+		"main":    geometry.NewRect(0, 0, 60, 30),
+		"map":     geometry.NewRect(0, 0, 60, 25),
+		"log":     geometry.NewRect(0, 25, 60, 30),
+		"sidebar": geometry.NewRect(60, 0, 80, 30),
+	}
+	if !reflect.DeepEqual(rects, want) {
+		t.Errorf("Compute() = %v, want %v", rects, want)
+	}
+	if lay.Update(geometry.Point{X: 80, Y: 30}) {
+		t.Error("Update() = true for the same size")
+	}
+	if !lay.Update(geometry.Point{X: 40, Y: 30}) || lay.Rect("sidebar") != geometry.NewRect(24, 0, 40, 30) {
+		t.Errorf("sidebar after Update = %v, want its minimum width", lay.Rect("sidebar"))
+	}
+}
@@ -3,14 +3,19 @@ package main
 import (
 	"embed"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"runtime/pprof"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 
 	"github.com/memmaker/ECon/common"
 	"github.com/memmaker/ECon/console"
+	"github.com/memmaker/ECon/console/ebitenrenderer"
+	"github.com/memmaker/ECon/console/offscreen"
+	"github.com/memmaker/ECon/console/tileset"
 	"github.com/memmaker/ECon/game"
 	"github.com/memmaker/ECon/geometry"
 	"github.com/memmaker/ECon/input"
@@ -20,31 +25,92 @@ import (
 var embeddedFS embed.FS
 
 var cpuprofile = flag.String("cpuprofile", "", "write cpu profile to `file`")
+var recordFile = flag.String("record", "", "record the console frames to `file`")
+var tilesetFile = flag.String("tileset", "", "draw the cells with the tiles of a CP437 PNG atlas `file` instead of the fonts")
+var resizable = flag.Bool("resizable", false, "make the window resizable, the grid size following the window size")
+var bindingsFile = flag.String("bindings", "", "load the key bindings of the actions from a JSON `file`")
+var recordInputFile = flag.String("recordinput", "", "record the input of each frame, with the random seed, to `file`")
+var replayInputFile = flag.String("replayinput", "", "replay the input recorded in `file` by -recordinput, then quit")
+var tileSize = flag.Int("tilesize", 16, "size in pixels of the square tiles of the -tileset atlas")
 
 type Game struct {
 	// Config
 	Config console.GridConfig
 	// Input
-	Input *InputState
+	Input   *InputState
+	Actions *input.ActionMap // bindings of the model actions and the screenshot
 	// Console
-	Console *console.Console
+	Console  *console.Console
+	Renderer *ebitenrenderer.Renderer
+	Painter  *offscreen.Painter // for screenshots
 	// Model
 	Model          *game.Model
 	deviceDPIScale float64
+	// Resizable mode
+	Resizable   bool
+	pendingSize geometry.Point // grid size for the window size, applied by Update
+	// Input recording and replay
+	InputRecorder *input.Recorder
+	Replay        *input.Replay // replaces Input when set
 }
 
 func (g *Game) GetInput() input.GridInput {
+	if g.Replay != nil {
+		return g.Replay
+	}
 	return g.Input
 }
 
 func (g *Game) Update() error {
+	g.applyResize()
 	g.pollInput()
+	if g.Replay != nil && !g.Replay.Next() {
+		log.Printf("replay over after %d frames", g.Replay.Len())
+		return ebiten.Termination
+	}
+	if g.InputRecorder != nil {
+		if err := g.InputRecorder.Record(g.GetInput()); err != nil {
+			return err
+		}
+	}
 	g.Model.Update(g)       // This is our model's update() call
 	g.Model.Draw(g.Console) // This is our model's draw() call
+	if g.Actions.IsTriggered(g.GetInput(), game.ActionScreenshot) {
+		g.takeScreenshot()
+	}
 	g.Console.Flush()
 	return nil
 }
 
+// takeScreenshot writes the console to a PNG file in the working directory.
+func (g *Game) takeScreenshot() {
+	path := fmt.Sprintf("screenshot_%s.png", time.Now().Format("20060102_150405"))
+	f, err := os.Create(path)
+	if err != nil {
+		log.Print(err)
+		return
+	}
+	defer f.Close()
+	if err := g.Console.WriteScreenshot(f, g.Painter); err != nil {
+		log.Print(err)
+		return
+	}
+	log.Printf("screenshot saved to %s", path)
+}
+
+// applyResize resizes the console to the grid size computed by LayoutF, and
+// notifies the model.
+func (g *Game) applyResize() {
+	size := g.pendingSize
+	if !g.Resizable || size == (geometry.Point{}) || size == g.Console.Size() {
+		return
+	}
+	g.Config.GridWidth = size.X
+	g.Config.GridHeight = size.Y
+	g.Console.Resize(size)
+	g.Model.OnResize(size)
+}
+
 func (g *Game) pollInput() {
 	// mouse
 	g.Input.LastMousePos = g.Input.MousePos
@@ -54,11 +120,19 @@ func (g *Game) pollInput() {
 	xMouse = common.Clamp(xMouse, 0, float64(g.Config.GridWidth-1))
 	yMouse = common.Clamp(yMouse, 0, float64(g.Config.GridHeight-1))
 	g.Input.MousePos = geometry.Point{X: int(xMouse), Y: int(yMouse)}
+	// half-width cells are hit-tested on their own, so that a click on the
+	// right half of a square cell selects the right half-width cell
+	xHalf := float64(mx) / (float64(g.Config.TileWidth) / 2 * g.deviceDPIScale)
+	xHalf = common.Clamp(xHalf, 0, float64(2*g.Config.GridWidth-1))
+	g.Input.HalfWidthMousePos = geometry.Point{X: int(xHalf), Y: int(yMouse)}
+	gridSize := geometry.Point{X: g.Config.GridWidth, Y: g.Config.GridHeight}
+	g.Input.pollGamepads(gridSize, geometry.Point{X: mx, Y: my})
+	g.Input.pollEvents()
 }
 
 // This is the draw() function of ebitengine. It will draw the console to the screen.
 func (g *Game) Draw(screen *ebiten.Image) {
-	g.Console.Draw(screen)
+	g.Renderer.Draw(screen)
 }
 
 func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeight int) {
@@ -68,7 +142,18 @@ func (g *Game) Layout(outsideWidth, outsideHeight int) (screenWidth, screenHeigh
 func (g *Game) LayoutF(outsideWidth, outsideHeight float64) (screenWidth, screenHeight float64) {
 	scale := ebiten.DeviceScaleFactor()
 	g.deviceDPIScale = scale
-	g.Console.SetScale(scale)
+	g.Renderer.SetScale(scale)
+	if g.Resizable {
+		// as many whole tiles as fit, the remaining pixels stay black
+		g.pendingSize = geometry.Point{
+			X: int(outsideWidth) / g.Config.TileWidth,
+			Y: int(outsideHeight) / g.Config.TileHeight,
+		}
+		if g.pendingSize.X < 1 || g.pendingSize.Y < 1 {
+			g.pendingSize = geometry.Point{X: 1, Y: 1}
+		}
+		return outsideWidth * scale, outsideHeight * scale
+	}
 	return float64(g.Config.GridWidth*g.Config.TileWidth) * scale, float64(g.Config.GridHeight*g.Config.TileHeight) * scale
 }
 
@@ -79,6 +164,11 @@ func (g *Game) Init() {
 
 func main() {
 	flag.Parse()
+	if *recordInputFile != "" && *resizable {
+		// the recording only has the initial grid size, which the recorded
+		// mouse positions depend on
+		log.Fatal("-recordinput can't be used with -resizable")
+	}
 	if *cpuprofile != "" {
 		f, err := os.Create(*cpuprofile)
 		if err != nil {
@@ -93,7 +183,7 @@ func main() {
 
 	gameTitle := "E-Console"
 	fontDirectory := "embedded/font"
-	fontName := "Square"
+	fontNames := []string{"Square"}
 
 	config := console.GridConfig{
 		TileWidth:  20,
@@ -101,22 +191,120 @@ func main() {
 		GridWidth:  64,
 		GridHeight: 36,
 	}
+	seed := time.Now().UnixNano()
+	var replay *input.Replay
+	if *replayInputFile != "" {
+		rec, err := readInputRecording(*replayInputFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// the recorded mouse positions need the recorded grid size
+		config.GridWidth, config.GridHeight = rec.Width, rec.Height
+		seed = rec.Seed
+		replay = input.NewReplay(rec)
+	}
+	var tiles *tileset.Tileset
+	if *tilesetFile != "" {
+		var err error
+		tiles, err = tileset.LoadFile(*tilesetFile, *tileSize, *tileSize, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		config.RenderMode = console.TilesetRendering
+	}
 
-	con := console.NewConsole(config)
-	con.LoadEmbeddedFont(fontDirectory, fontName, embeddedFS)
+	renderer := ebitenrenderer.NewRenderer(config)
+	renderer.LoadEmbeddedFont(fontDirectory, fontNames, embeddedFS)
+	if tiles != nil {
+		renderer.SetTileset(tiles)
+	}
+	// the screenshots use the fonts of the window
+	painter, err := offscreen.NewFallbackPainter(renderer.Fonts(), config.TileWidth, config.TileHeight)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if tiles != nil {
+		painter.SetTileset(tiles)
+	}
+	actions := game.DefaultActions()
+	if *bindingsFile != "" {
+		bindings, err := input.LoadFile(*bindingsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		actions.Merge(bindings)
+		for _, conflict := range actions.Conflicts() {
+			log.Printf("binding conflict: %s", conflict)
+		}
+	}
+	if missing := renderer.MissingRunes(game.UsedRunes()); len(missing) > 0 {
+		log.Printf("runes not covered by the fonts %v or the tileset: %q", fontNames, missing)
+	}
 	consoleGame := &Game{
-		Config:  config,
-		Console: con,
-		Input:   NewInput(),
-		Model:   game.NewModel(config),
+		Config:    config,
+		Console:   console.NewConsole(config, renderer),
+		Renderer:  renderer,
+		Painter:   painter,
+		Input:     NewInput(),
+		Model:     game.NewModel(config),
+		Resizable: *resizable && replay == nil,
+		Replay:    replay,
 	}
 	ebiten.SetWindowTitle(gameTitle)
 	ebiten.SetWindowSize(int(float64(config.GridWidth*config.TileWidth)), int(float64(config.GridHeight*config.TileHeight)))
 	ebiten.SetScreenClearedEveryFrame(false)
+	if *resizable {
+		ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
+	}
+	consoleGame.Actions = actions
+	consoleGame.Input.Actions = actions
+	consoleGame.Model.SetActions(actions)
+	consoleGame.Model.SetSeed(seed)
 	consoleGame.Init()
+	if *recordFile != "" {
+		f, err := os.Create(*recordFile)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		if err := consoleGame.Console.StartRecording(f); err != nil {
+			panic(err)
+		}
+		defer func() {
+			if err := consoleGame.Console.StopRecording(); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
+	if *recordInputFile != "" {
+		f, err := os.Create(*recordInputFile)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		header := input.RecordingHeader{Seed: seed, Width: config.GridWidth, Height: config.GridHeight}
+		consoleGame.InputRecorder, err = input.NewRecorder(f, header)
+		if err != nil {
+			panic(err)
+		}
+		defer func() {
+			if err := consoleGame.InputRecorder.Close(); err != nil {
+				log.Print(err)
+			}
+		}()
+	}
 	if err := ebiten.RunGameWithOptions(consoleGame, &ebiten.RunGameOptions{
 		GraphicsLibrary: ebiten.GraphicsLibraryOpenGL,
 	}); err != nil {
 		log.Fatal(err)
 	}
 }
+
+func readInputRecording(path string) (*input.Recording, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return input.ReadRecording(f)
+}
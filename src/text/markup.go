@@ -0,0 +1,153 @@
+package text
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/memmaker/ECon/common"
+)
+
+// Style is the colors of printed runes. A nil color keeps the color of the
+// cell printed over, for example the background of a panel.
+type Style struct {
+	Fg color.Color
+	Bg color.Color
+}
+
+// FromStyle returns the text style of a common.Style.
+func FromStyle(st common.Style) Style {
+	return Style{Fg: st.Fg, Bg: st.Bg}
+}
+
+// StyledRune is a rune with the style given by the markup around it.
+type StyledRune struct {
+	Rune  rune
+	Style Style
+}
+
+// namedColors are the colors accepted by name in the markup.
+var namedColors = map[string]common.RGBColor{
+	"white":   common.White,
+	"black":   common.Black,
+	"red":     common.Red,
+	"green":   common.Green,
+	"blue":    common.Blue,
+	"yellow":  {R: 1, G: 1},
+	"cyan":    {G: 1, B: 1},
+	"magenta": {R: 1, B: 1},
+	"gray":    {R: 0.5, G: 0.5, B: 0.5},
+	"grey":    {R: 0.5, G: 0.5, B: 0.5},
+}
+
+// Parse splits a text with inline style markup into styled runes.
+//
+// A tag like [fg=#ff0000] or [fg=red bg=#222] starts a style that lasts until
+// the matching [/]. Tags nest: [/] goes back to the style before its opening
+// tag. Colors are #rgb or #rrggbb hexadecimal values, the names of
+// namedColors, or "default" for the color of the base style. [[ is a
+// literal '['. A malformed tag is an error.
+func Parse(markup string, base Style) ([]StyledRune, error) {
+	runes := make([]StyledRune, 0, len(markup))
+	stack := []Style{base}
+	for i := 0; i < len(markup); {
+		r, size := utf8.DecodeRuneInString(markup[i:])
+		current := stack[len(stack)-1]
+		if r != '[' {
+			runes = append(runes, StyledRune{Rune: r, Style: current})
+			i += size
+			continue
+		}
+		if strings.HasPrefix(markup[i:], "[[") {
+			runes = append(runes, StyledRune{Rune: '[', Style: current})
+			i += 2
+			continue
+		}
+		end := strings.IndexByte(markup[i:], ']')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed tag at offset %d", i)
+		}
+		tag := markup[i+1 : i+end]
+		i += end + 1
+		if tag == "/" {
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("[/] without opening tag at offset %d", i-3)
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		st, err := parseTag(tag, current, base)
+		if err != nil {
+			return nil, err
+		}
+		stack = append(stack, st)
+	}
+	return runes, nil
+}
+
+func parseTag(tag string, current, base Style) (Style, error) {
+	fields := strings.Fields(tag)
+	if len(fields) == 0 {
+		return current, fmt.Errorf("empty tag []")
+	}
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return current, fmt.Errorf("invalid attribute %q in tag [%s]", field, tag)
+		}
+		switch key {
+		case "fg":
+			c, err := parseColor(value, base.Fg)
+			if err != nil {
+				return current, err
+			}
+			current.Fg = c
+		case "bg":
+			c, err := parseColor(value, base.Bg)
+			if err != nil {
+				return current, err
+			}
+			current.Bg = c
+		default:
+			return current, fmt.Errorf("unknown attribute %q in tag [%s]", key, tag)
+		}
+	}
+	return current, nil
+}
+
+// ParseColor parses a color of the markup: #rgb, #rrggbb or the name of a
+// named color.
+func ParseColor(s string) (common.RGBColor, error) {
+	if c, ok := namedColors[strings.ToLower(s)]; ok {
+		return c, nil
+	}
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == len(s) || len(hex) != 3 && len(hex) != 6 {
+		return common.RGBColor{}, fmt.Errorf("invalid color %q", s)
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return common.RGBColor{}, fmt.Errorf("invalid color %q", s)
+	}
+	return common.RGBColor{
+		R: float64(v>>16&0xff) / 255,
+		G: float64(v>>8&0xff) / 255,
+		B: float64(v&0xff) / 255,
+	}, nil
+}
+
+func parseColor(s string, def color.Color) (color.Color, error) {
+	if s == "default" {
+		return def, nil
+	}
+	c, err := ParseColor(s)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
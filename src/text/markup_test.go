@@ -0,0 +1,104 @@
+package text_test
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/text"
+)
+
+func TestParse(t *testing.T) {
+	base := text.Style{Fg: common.White}
+	blue := common.RGBColor{B: 1}
+	tests := []struct {
+		markup string
+		want   []text.StyledRune
+	}{
+		{"ab", []text.StyledRune{{'a', base}, {'b', base}}},
+		{"[fg=red]a[/]b", []text.StyledRune{{'a', text.Style{Fg: common.Red}}, {'b', base}}},
+		{"[fg=red][bg=#00f]a[/]b[/]c", []text.StyledRune{
+			{'a', text.Style{Fg: common.Red, Bg: blue}},
+			{'b', text.Style{Fg: common.Red}},
+			{'c', base},
+		}},
+		{"[fg=#f00 bg=#0000ff]a", []text.StyledRune{{'a', text.Style{Fg: common.Red, Bg: blue}}}},
+		{"[fg=RED]a[fg=default]b", []text.StyledRune{{'a', text.Style{Fg: common.Red}}, {'b', base}}},
+		{"[[a]", []text.StyledRune{{'[', base}, {'a', base}, {']', base}}},
+		{"", []text.StyledRune{}},
+	}
+	for _, tt := range tests {
+		got, err := text.Parse(tt.markup, base)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.markup, err)
+			continue
+		}
+		if !sameRunes(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.markup, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, markup := range []string{
+		"[fg=red",
+		"a[/]",
+		"[]",
+		"[fg]",
+		"[fg=zzz]",
+		"[fg=#ff00]",
+		"[size=2]",
+	} {
+		if _, err := text.Parse(markup, text.Style{}); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", markup)
+		}
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := text.ParseColor("#ff8000")
+	if want := (common.RGBColor{R: 1, G: 128.0 / 255}); err != nil || c != want {
+		t.Errorf("ParseColor(#ff8000) = %v, %v, want %v", c, err, want)
+	}
+	if c, err := text.ParseColor("Gray"); err != nil || c != (common.RGBColor{R: 0.5, G: 0.5, B: 0.5}) {
+		t.Errorf("ParseColor(Gray) = %v, %v, want the gray color", c, err)
+	}
+	if _, err := text.ParseColor("ff8000"); err == nil {
+		t.Error("ParseColor accepts a color without #")
+	}
+}
+
+func TestPlain(t *testing.T) {
+	tests := []struct{ markup, want string }{
+		{"[fg=red]hi[/] there", "hi there"},
+		{"[[x]", "[x]"},
+		{"[oops", "[oops"},
+	}
+	for _, tt := range tests {
+		if got := text.Plain(tt.markup); got != tt.want {
+			t.Errorf("Plain(%q) = %q, want %q", tt.markup, got, tt.want)
+		}
+	}
+}
+
+// sameRunes compares styled runes, the colors by value.
+func sameRunes(a, b []text.StyledRune) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Rune != b[i].Rune || !sameColor(a[i].Style.Fg, b[i].Style.Fg) || !sameColor(a[i].Style.Bg, b[i].Style.Bg) {
+			return false
+		}
+	}
+	return true
+}
+
+func sameColor(a, b color.Color) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
@@ -0,0 +1,230 @@
+// Package text prints strings with inline style markup into console cells,
+// with word wrapping, alignment and truncation.
+package text
+
+import (
+	"strings"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// Align is the horizontal alignment of the lines within the printed range.
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// Ellipsis replaces the last rune of a truncated line.
+const Ellipsis = '…'
+
+// Options control how Print lays out a text.
+type Options struct {
+	Style Style // style outside of the markup tags
+	Align Align
+	// Wrap breaks the lines longer than the range at spaces, or within the
+	// words longer than a line. Without Wrap, long lines are truncated.
+	Wrap bool
+	// Raw disables the markup: the text is printed as is.
+	Raw bool
+}
+
+// Canvas is the part of console.CellInterface used to print, also implemented
+// by geometry.Grid.
+type Canvas interface {
+	Set(p geometry.Point, cell common.Cell)
+	At(p geometry.Point) common.Cell
+}
+
+// Print prints a text into a range of cells of con and returns the number of
+// lines used. Newlines start new lines. Lines that don't fit in the range, in
+// width without Wrap, or in height, are truncated with an Ellipsis. Text with
+// malformed markup is printed raw.
+func Print(con Canvas, rg geometry.Rect, s string, opts Options) int {
+	lines := Layout(s, rg.Size().X, opts)
+	height := rg.Size().Y
+	if len(lines) > height {
+		lines = lines[:height]
+		if height > 0 {
+			lines[height-1] = truncate(lines[height-1], rg.Size().X, true)
+		}
+	}
+	PrintLines(con, rg, lines, opts.Align)
+	return len(lines)
+}
+
+// PrintLines prints lines laid out by Layout into a range, one per row, with
+// the given alignment. The runes and lines beyond the range are clipped.
+func PrintLines(con Canvas, rg geometry.Rect, lines [][]StyledRune, align Align) {
+	size := rg.Size()
+	for y, line := range lines {
+		if y >= size.Y {
+			return
+		}
+		x := alignOffset(len(line), size.X, align)
+		for i, sr := range line {
+			if x+i < 0 || x+i >= size.X {
+				continue
+			}
+			p := rg.Min.Shift(x+i, y)
+			con.Set(p, styledCell(con.At(p), sr))
+		}
+	}
+}
+
+// Measure returns the number of lines Print would use to print a text with
+// the given width and an unbounded height.
+func Measure(s string, width int, opts Options) int {
+	return len(Layout(s, width, opts))
+}
+
+// Layout splits a text into the lines Print would use for the given width
+// and an unbounded height. The lines are not aligned.
+func Layout(s string, width int, opts Options) [][]StyledRune {
+	runes := parseOrRaw(s, opts)
+	var lines [][]StyledRune
+	for _, paragraph := range splitLines(runes) {
+		if !opts.Wrap {
+			lines = append(lines, truncate(paragraph, width, false))
+			continue
+		}
+		lines = append(lines, wrap(paragraph, width)...)
+	}
+	return lines
+}
+
+func parseOrRaw(s string, opts Options) []StyledRune {
+	if !opts.Raw {
+		if runes, err := Parse(s, opts.Style); err == nil {
+			return runes
+		}
+	}
+	runes := make([]StyledRune, 0, len(s))
+	for _, r := range s {
+		runes = append(runes, StyledRune{Rune: r, Style: opts.Style})
+	}
+	return runes
+}
+
+func splitLines(runes []StyledRune) [][]StyledRune {
+	var lines [][]StyledRune
+	start := 0
+	for i, sr := range runes {
+		if sr.Rune == '\n' {
+			lines = append(lines, runes[start:i])
+			start = i + 1
+		}
+	}
+	return append(lines, runes[start:])
+}
+
+// wrap breaks a line into lines of at most width runes, at spaces when
+// possible. The spaces at the breaks are dropped. The leading spaces of the
+// line are kept as an indentation, unless they fill a whole line.
+func wrap(line []StyledRune, width int) [][]StyledRune {
+	if width <= 0 {
+		return nil
+	}
+	var lines [][]StyledRune
+	for len(line) > width {
+		indent := 0
+		for indent < len(line) && line[indent].Rune == ' ' {
+			indent++
+		}
+		if indent >= width {
+			line = line[indent:]
+			continue
+		}
+		// a break within the indentation would give an empty line
+		cut := -1
+		for i := width; i > indent; i-- {
+			if line[i].Rune == ' ' {
+				cut = i
+				break
+			}
+		}
+		if cut < 0 {
+			// a word longer than the line
+			lines = append(lines, line[:width])
+			line = line[width:]
+			continue
+		}
+		lines = append(lines, trimSpaces(line[:cut]))
+		line = line[cut:]
+		for len(line) > 0 && line[0].Rune == ' ' {
+			line = line[1:]
+		}
+	}
+	return append(lines, line)
+}
+
+func trimSpaces(line []StyledRune) []StyledRune {
+	for len(line) > 0 && line[len(line)-1].Rune == ' ' {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// truncate shortens a line to width runes, replacing the last one with an
+// Ellipsis if the line was cut or if force is set.
+func truncate(line []StyledRune, width int, force bool) []StyledRune {
+	if width <= 0 {
+		return nil
+	}
+	if len(line) <= width && !force {
+		return line
+	}
+	if len(line) > width {
+		line = line[:width]
+	}
+	truncated := make([]StyledRune, len(line), width)
+	copy(truncated, line)
+	if len(truncated) < width {
+		// room left after a short last line
+		style := Style{}
+		if len(line) > 0 {
+			style = line[len(line)-1].Style
+		}
+		return append(truncated, StyledRune{Rune: Ellipsis, Style: style})
+	}
+	truncated[width-1].Rune = Ellipsis
+	return truncated
+}
+
+func alignOffset(length, width int, align Align) int {
+	switch align {
+	case AlignCenter:
+		return (width - length) / 2
+	case AlignRight:
+		return width - length
+	}
+	return 0
+}
+
+func styledCell(below common.Cell, sr StyledRune) common.Cell {
+	cell := common.Cell{Char: sr.Rune, Foreground: sr.Style.Fg, Background: sr.Style.Bg}
+	if cell.Foreground == nil {
+		cell.Foreground = below.Foreground
+	}
+	if cell.Background == nil {
+		cell.Background = below.Background
+	}
+	return cell
+}
+
+// Plain returns a text without its markup, or the text itself if its markup
+// is malformed.
+func Plain(s string) string {
+	runes, err := Parse(s, Style{})
+	if err != nil {
+		return s
+	}
+	var b strings.Builder
+	for _, sr := range runes {
+		b.WriteRune(sr.Rune)
+	}
+	return b.String()
+}
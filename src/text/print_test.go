@@ -0,0 +1,124 @@
+package text_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/text"
+)
+
+// lineStrings returns the runes of laid out lines.
+func lineStrings(lines [][]text.StyledRune) []string {
+	strs := make([]string, len(lines))
+	for i, line := range lines {
+		var b strings.Builder
+		for _, sr := range line {
+			b.WriteRune(sr.Rune)
+		}
+		strs[i] = b.String()
+	}
+	return strs
+}
+
+func TestLayoutWrap(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  []string
+	}{
+		{"hello world", 11, []string{"hello world"}},
+		{"hello world", 5, []string{"hello", "world"}},
+		{"hello   world", 5, []string{"hello", "world"}},
+		{"a bb ccc dddd", 6, []string{"a bb", "ccc", "dddd"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"one\ntwo three", 5, []string{"one", "two", "three"}},
+		{"[fg=red]hello[/] world", 5, []string{"hello", "world"}},
+		{"  hello world", 8, []string{"  hello", "world"}},
+		{"  abcdefgh ij", 6, []string{"  abcd", "efgh", "ij"}},
+		{"      abc", 4, []string{"abc"}},
+		{"", 5, []string{""}},
+	}
+	for _, tt := range tests {
+		got := lineStrings(text.Layout(tt.s, tt.width, text.Options{Wrap: true}))
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("Layout(%q, %d) = %q, want %q", tt.s, tt.width, got, tt.want)
+		}
+		if n := text.Measure(tt.s, tt.width, text.Options{Wrap: true}); n != len(tt.want) {
+			t.Errorf("Measure(%q, %d) = %d, want %d", tt.s, tt.width, n, len(tt.want))
+		}
+	}
+}
+
+func TestLayoutTruncate(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		opts  text.Options
+		want  []string
+	}{
+		{"hello world", 5, text.Options{}, []string{"hell…"}},
+		{"hello", 5, text.Options{}, []string{"hello"}},
+		{"hello\nbeautiful world", 6, text.Options{}, []string{"hello", "beaut…"}},
+		{"[fg=red]abc[/]", 3, text.Options{}, []string{"abc"}},
+		{"[fg=red]abc[/]", 3, text.Options{Raw: true}, []string{"[f…"}},
+		{"[bad", 10, text.Options{}, []string{"[bad"}},
+	}
+	for _, tt := range tests {
+		got := lineStrings(text.Layout(tt.s, tt.width, tt.opts))
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("Layout(%q, %d, %+v) = %q, want %q", tt.s, tt.width, tt.opts, got, tt.want)
+		}
+	}
+}
+
+// newCanvas returns a grid of blanks.
+func newCanvas(w, h int) geometry.Grid {
+	gd := geometry.NewGrid(w, h)
+	gd.Fill(common.Cell{Char: ' ', Foreground: common.White, Background: common.Black})
+	return gd
+}
+
+func TestPrint(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		opts  text.Options
+		lines int
+		want  string
+	}{
+		{"left", "ab", text.Options{}, 1, "ab   \n     \n"},
+		{"center", "ab", text.Options{Align: text.AlignCenter}, 1, " ab  \n     \n"},
+		{"right", "ab\nc", text.Options{Align: text.AlignRight}, 2, "   ab\n    c\n"},
+		{"wrapped", "hello big", text.Options{Wrap: true}, 2, "hello\nbig  \n"},
+		{"too many lines", "hello big world", text.Options{Wrap: true}, 2, "hello\nbig… \n"},
+		{"indented", "  abcdefgh", text.Options{Wrap: true}, 2, "  abc\ndefgh\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCanvas(5, 2)
+			if n := text.Print(c, c.Range(), tt.s, tt.opts); n != tt.lines {
+				t.Errorf("Print() = %d lines, want %d", n, tt.lines)
+			}
+			if got := c.String(); got != tt.want {
+				t.Errorf("printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintStyles(t *testing.T) {
+	c := newCanvas(3, 1)
+	text.Print(c, c.Range(), "[fg=red]a[/][bg=blue]b[/]c", text.Options{})
+	want := []common.Cell{
+		{Char: 'a', Foreground: common.Red, Background: common.Black},
+		{Char: 'b', Foreground: common.White, Background: common.Blue},
+		{Char: 'c', Foreground: common.White, Background: common.Black},
+	}
+	for x, cell := range want {
+		if got := c.At(geometry.Point{X: x}); got != cell {
+			t.Errorf("cell %d = %+v, want %+v", x, got, cell)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+// Package ui is an immediate-mode widget toolkit drawing into a Canvas, like
+// a console.CellInterface or a geometry.Grid slice, and reading a
+// input.GridInput.
+//
+// Widgets are plain method calls on a Context, made every frame between Begin
+// and End: each call handles the input of the frame for the widget, draws it,
+// and returns what the user did with it. The Context keeps the little state
+// that must survive between frames, like the focus or the scroll offsets,
+// keyed by the widget IDs.
+//
+// As models get their input in Update and draw in Draw, a model usually calls
+// Begin in Update and the widgets and End in Draw:
+//
+//	func (m *Model) Update(engine console.Engine) {
+//		m.ui.Begin(engine.GetInput())
+//	}
+//
+//	func (m *Model) Draw(con console.CellInterface) {
+//		if m.ui.Button(con, "ok", geometry.NewRect(2, 2, 10, 3), "OK") {
+//			m.confirmed = true
+//		}
+//		m.ui.End(con)
+//	}
+//
+// The focused widget receives the keys: Tab, and the menu up and down keys
+// when the widget doesn't use them, move the focus between the widgets in the
+// order they were called. Enter or Space activate the focused widget.
+package ui
+
+import (
+	"github.com/memmaker/ECon/box"
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/input"
+	"github.com/memmaker/ECon/text"
+)
+
+// Canvas is what widgets draw into, implemented by console.CellInterface and
+// geometry.Grid.
+type Canvas interface {
+	text.Canvas
+	Size() geometry.Point
+}
+
+// ID identifies a widget across frames. IDs must be unique within a frame.
+type ID string
+
+// Theme is the look of the widgets.
+type Theme struct {
+	Normal   text.Style // idle widgets
+	Hover    text.Style // widgets under the mouse
+	Focused  text.Style // the focused widget
+	Selected text.Style // selected list items
+	Panel    text.Style // menus, dialogs and tooltips
+	Lines    box.LineStyle
+	// TooltipDelay is the number of frames the mouse has to stay still over
+	// a widget before its tooltip shows.
+	TooltipDelay int
+}
+
+func DefaultTheme() Theme {
+	return Theme{
+		Normal:       text.Style{Fg: common.RGBColor{R: 0.8, G: 0.8, B: 0.8}, Bg: common.RGBColor{R: 0.15, G: 0.15, B: 0.2}},
+		Hover:        text.Style{Fg: common.White, Bg: common.RGBColor{R: 0.25, G: 0.25, B: 0.35}},
+		Focused:      text.Style{Fg: common.White, Bg: common.RGBColor{R: 0.3, G: 0.4, B: 0.7}},
+		Selected:     text.Style{Fg: common.Black, Bg: common.RGBColor{R: 0.8, G: 0.8, B: 0.8}},
+		Panel:        text.Style{Fg: common.White, Bg: common.RGBColor{R: 0.1, G: 0.1, B: 0.15}},
+		Lines:        box.Single,
+		TooltipDelay: 30,
+	}
+}
+
+// thumb returns the rune of the slider knobs and the scroll bar thumbs: a full
+// block, or '#' with the ASCII lines.
+func (th Theme) thumb() rune {
+	if th.Lines == box.ASCII {
+		return '#'
+	}
+	return '█'
+}
+
+// Context is the state of the UI across frames.
+type Context struct {
+	Theme Theme
+	// HalfWidth makes the widgets use the half-width mouse position, for
+	// widgets drawn into console.Console.HalfWidth.
+	HalfWidth bool
+
+	in    input.GridInput
+	keys  []string
+	mouse geometry.Point
+	// focus
+	focus          ID
+	focusables     []ID // focusable widgets of the current frame, in call order
+	lastFocusables []ID
+	usedVertical   bool // the focused widget used the menu up and down keys
+	// modal dialogs
+	modal     ID // dialog shown in the current frame
+	lastModal ID // dialog shown in the previous frame
+	inModal   bool
+	// per widget state
+	scroll map[ID]int
+	// tooltips
+	stillFrames int // frames since the mouse last moved
+	tooltip     string
+	tooltipPos  geometry.Point
+}
+
+func NewContext() *Context {
+	return &Context{
+		Theme:  DefaultTheme(),
+		scroll: make(map[ID]int),
+	}
+}
+
+// Begin starts a frame with its input.
+func (ctx *Context) Begin(in input.GridInput) {
+	ctx.in = in
+	ctx.keys = in.GetJustPressedKeys()
+	if ctx.HalfWidth {
+		ctx.mouse = in.GetHalfWidthMousePos()
+	} else {
+		ctx.mouse = in.GetMousePos()
+	}
+	if in.HasMouseMoved() {
+		ctx.stillFrames = 0
+	} else {
+		ctx.stillFrames++
+	}
+	ctx.lastFocusables, ctx.focusables = ctx.focusables, ctx.lastFocusables[:0]
+	ctx.lastModal, ctx.modal = ctx.modal, ""
+	ctx.usedVertical = false
+	ctx.tooltip = ""
+}
+
+// End moves the focus according to the keys of the frame and draws the
+// tooltip, if any. It must be called after all the widgets.
+func (ctx *Context) End(c Canvas) {
+	ctx.navigate()
+	if ctx.tooltip != "" {
+		ctx.drawTooltip(c)
+	}
+}
+
+func (ctx *Context) navigate() {
+	n := len(ctx.focusables)
+	if n == 0 {
+		return
+	}
+	i := -1
+	for j, id := range ctx.focusables {
+		if id == ctx.focus {
+			i = j
+		}
+	}
+	switch {
+	case ctx.IsKeyPressed("Tab"):
+		i = (i + 1) % n
+	case !ctx.usedVertical && ctx.in.IsMenuDown():
+		i = (i + 1) % n
+	case !ctx.usedVertical && ctx.in.IsMenuUp() && i > 0:
+		i--
+	case !ctx.usedVertical && ctx.in.IsMenuUp():
+		i = n - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	ctx.focus = ctx.focusables[i]
+}
+
+// Focus returns the ID of the focused widget.
+func (ctx *Context) Focus() ID {
+	return ctx.focus
+}
+
+// SetFocus gives the focus to a widget.
+func (ctx *Context) SetFocus(id ID) {
+	ctx.focus = id
+}
+
+// IsKeyPressed reports whether a key was pressed in the frame, see
+// input.GridInput.GetJustPressedKeys for the key names.
+func (ctx *Context) IsKeyPressed(key string) bool {
+	for _, k := range ctx.keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// blocked reports whether the input is reserved for a modal dialog the
+// current widget is not part of.
+func (ctx *Context) blocked() bool {
+	return ctx.lastModal != "" && !ctx.inModal
+}
+
+// interaction is what the input of the frame did to a widget.
+type interaction struct {
+	hovered   bool
+	clicked   bool
+	focused   bool
+	activated bool // clicked, or Enter or Space pressed while focused
+}
+
+// interact registers a focusable widget covering rg and returns its
+// interaction. A click focuses the widget.
+func (ctx *Context) interact(id ID, rg geometry.Rect) interaction {
+	if ctx.in == nil || ctx.blocked() {
+		return interaction{}
+	}
+	ctx.focusables = append(ctx.focusables, id)
+	var it interaction
+	it.hovered = ctx.mouse.In(rg)
+	it.clicked = it.hovered && ctx.in.IsMouseLeft()
+	if it.clicked {
+		ctx.focus = id
+	}
+	it.focused = ctx.focus == id
+	it.activated = it.clicked || it.focused && (ctx.IsKeyPressed("Enter") || ctx.IsKeyPressed("Space"))
+	return it
+}
+
+// style returns the theme style of a widget.
+func (ctx *Context) style(it interaction) text.Style {
+	switch {
+	case it.focused:
+		return ctx.Theme.Focused
+	case it.hovered:
+		return ctx.Theme.Hover
+	}
+	return ctx.Theme.Normal
+}
+
+// fill fills a range with spaces of the given style.
+func fill(c Canvas, rg geometry.Rect, st text.Style) {
+	rg.Iter(func(p geometry.Point) {
+		below := c.At(p)
+		cell := common.Cell{Char: ' ', Foreground: st.Fg, Background: st.Bg}
+		if cell.Foreground == nil {
+			cell.Foreground = below.Foreground
+		}
+		if cell.Background == nil {
+			cell.Background = below.Background
+		}
+		c.Set(p, cell)
+	})
+}
+
+// Tooltip shows a tip next to the mouse when it stays over rg for
+// Theme.TooltipDelay frames. It is usually called right after the widget it
+// describes, with the same range.
+func (ctx *Context) Tooltip(rg geometry.Rect, tip string) {
+	if ctx.in == nil || ctx.blocked() || !ctx.mouse.In(rg) || ctx.stillFrames < ctx.Theme.TooltipDelay {
+		return
+	}
+	ctx.tooltip = tip
+	ctx.tooltipPos = ctx.mouse
+}
+
+// maxTooltipWidth is the width beyond which tooltips are wrapped.
+const maxTooltipWidth = 30
+
+func (ctx *Context) drawTooltip(c Canvas) {
+	size := c.Size()
+	width := maxTooltipWidth
+	if w := len([]rune(text.Plain(ctx.tooltip))); w < width {
+		width = w
+	}
+	if width > size.X-2 {
+		width = size.X - 2
+	}
+	if width < 1 {
+		return
+	}
+	opts := text.Options{Style: ctx.Theme.Panel, Wrap: true}
+	lines := text.Measure(ctx.tooltip, width, opts)
+	rg := geometry.NewRect(0, 0, width+2, lines+2).Add(ctx.tooltipPos.Shift(1, 1))
+	rg = keepInside(rg, size)
+	box.Panel(c, rg, ctx.Theme.Lines, ctx.Theme.Panel)
+	text.Print(c, rg.Shift(1, 1, -1, -1), ctx.tooltip, opts)
+}
+
+// keepInside moves a range so that it fits in a canvas of the given size, as
+// far as possible.
+func keepInside(rg geometry.Rect, size geometry.Point) geometry.Rect {
+	if rg.Max.X > size.X {
+		rg = rg.Add(geometry.Point{X: size.X - rg.Max.X})
+	}
+	if rg.Max.Y > size.Y {
+		rg = rg.Add(geometry.Point{Y: size.Y - rg.Max.Y})
+	}
+	if rg.Min.X < 0 {
+		rg = rg.Add(geometry.Point{X: -rg.Min.X})
+	}
+	if rg.Min.Y < 0 {
+		rg = rg.Add(geometry.Point{Y: -rg.Min.Y})
+	}
+	return rg
+}
@@ -0,0 +1,77 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/memmaker/ECon/box"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/text"
+)
+
+// maxDialogWidth is the width beyond which dialog messages are wrapped.
+const maxDialogWidth = 50
+
+// Dialog draws a modal dialog centered in the canvas, with a title, a message
+// and a row of buttons. While a dialog is shown, the other widgets ignore the
+// input, from the frame after the one it first appeared in; it is thus best
+// called after the other widgets. The first button gets the focus when the
+// dialog opens.
+//
+// Dialog returns done when a button was activated, with its index, or when the
+// dialog was closed with the menu close key, with -1. The caller stops calling
+// Dialog once done.
+func (ctx *Context) Dialog(c Canvas, id ID, title, message string, buttons []string) (choice int, done bool) {
+	ctx.modal = id
+	ctx.inModal = true
+	defer func() { ctx.inModal = false }()
+
+	buttonsWidth := 0
+	for _, label := range buttons {
+		buttonsWidth += len([]rune(text.Plain(label))) + 5 // padding and gap
+	}
+	size := c.Size()
+	width := len([]rune(text.Plain(message)))
+	if width < buttonsWidth {
+		width = buttonsWidth
+	}
+	if w := len([]rune(title)) + 2; width < w {
+		width = w
+	}
+	if width > maxDialogWidth {
+		width = maxDialogWidth
+	}
+	if width > size.X-4 {
+		width = size.X - 4
+	}
+	opts := text.Options{Style: ctx.Theme.Panel, Wrap: true, Align: text.AlignCenter}
+	lines := text.Measure(message, width, opts)
+	// frame, padding, message, empty line, buttons
+	rg := geometry.NewRect(0, 0, width+4, lines+5)
+	rg = rg.Add(size.Sub(rg.Size()).Div(2))
+
+	box.Panel(c, rg, ctx.Theme.Lines, ctx.Theme.Panel)
+	box.TitledFrame(c, rg, ctx.Theme.Lines, ctx.Theme.Panel, title, text.AlignCenter)
+	text.Print(c, rg.Shift(2, 2, -2, -3), message, opts)
+
+	if ctx.lastModal != id && len(buttons) > 0 {
+		ctx.focus = buttonID(id, 0)
+	}
+	x := rg.Min.X + (rg.Size().X-buttonsWidth)/2 + 1
+	y := rg.Max.Y - 2
+	choice, done = -1, false
+	for i, label := range buttons {
+		w := len([]rune(text.Plain(label))) + 4
+		if ctx.Button(c, buttonID(id, i), geometry.NewRect(x, y, x+w, y+1), label) {
+			choice, done = i, true
+		}
+		x += w + 1
+	}
+	if !done && ctx.in != nil && ctx.in.IsMenuClose() {
+		return -1, true
+	}
+	return choice, done
+}
+
+func buttonID(dialog ID, i int) ID {
+	return ID(fmt.Sprintf("%s/button%d", dialog, i))
+}
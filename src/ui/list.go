@@ -0,0 +1,119 @@
+package ui
+
+import (
+	"github.com/memmaker/ECon/box"
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/text"
+)
+
+// List draws a list of items, one per line, with the selected one
+// highlighted, and reports whether the selection was confirmed with a click
+// or the Enter key. The selection follows the mouse and the menu up and down
+// keys when the list is focused. When the items don't fit, the list scrolls to
+// keep the selection visible and shows a scroll bar in its last column. A list
+// without items leaves selected unchanged and is never confirmed.
+func (ctx *Context) List(c Canvas, id ID, rg geometry.Rect, items []string, selected *int) bool {
+	size := rg.Size()
+	if size.X < 1 || size.Y < 1 {
+		return false
+	}
+	it := ctx.interact(id, rg)
+	confirmed := false
+	if it.hovered && ctx.in.HasMouseMoved() || it.clicked {
+		if i := ctx.scroll[id] + ctx.mouse.Y - rg.Min.Y; i < len(items) {
+			*selected = i
+			confirmed = it.clicked
+		}
+	}
+	if it.focused && len(items) > 0 {
+		// without items, the menu up and down keys move the focus
+		ctx.usedVertical = true
+		switch {
+		case ctx.in.IsMenuUp():
+			*selected--
+		case ctx.in.IsMenuDown():
+			*selected++
+		case ctx.IsKeyPressed("Enter"):
+			confirmed = true
+		}
+	}
+	if len(items) > 0 {
+		*selected = clamp(*selected, 0, len(items)-1)
+	}
+
+	scrollBar := len(items) > size.Y
+	itemsRange := rg
+	if scrollBar {
+		itemsRange = rg.Shift(0, 0, -1, 0)
+	}
+	offset := ctx.scrollTo(id, *selected, len(items), size.Y)
+	st := ctx.style(it)
+	fill(c, rg, st)
+	for y := 0; y < size.Y && offset+y < len(items); y++ {
+		lineStyle := st
+		if offset+y == *selected {
+			lineStyle = ctx.Theme.Selected
+			fill(c, itemsRange.Line(y), lineStyle)
+		}
+		text.Print(c, itemsRange.Line(y), items[offset+y], text.Options{Style: lineStyle})
+	}
+	if scrollBar {
+		drawScrollBar(c, rg.Column(size.X-1), offset, len(items), st, ctx.Theme)
+	}
+	return confirmed
+}
+
+// scrollTo returns the scroll offset of a list, updated so that the selected
+// item is visible.
+func (ctx *Context) scrollTo(id ID, selected, count, height int) int {
+	offset := ctx.scroll[id]
+	if selected < offset {
+		offset = selected
+	}
+	if selected >= offset+height {
+		offset = selected - height + 1
+	}
+	offset = clamp(offset, 0, count-height)
+	if offset < 0 {
+		offset = 0
+	}
+	ctx.scroll[id] = offset
+	return offset
+}
+
+// drawScrollBar draws a vertical scroll bar whose thumb shows the visible part
+// of count lines, with the runes of the theme.
+func drawScrollBar(c Canvas, rg geometry.Rect, offset, count int, st text.Style, th Theme) {
+	height := rg.Size().Y
+	thumbSize := height * height / count
+	if thumbSize < 1 {
+		thumbSize = 1
+	}
+	thumb := 0
+	if count > height {
+		thumb = offset * (height - thumbSize) / (count - height)
+	}
+	for y := 0; y < height; y++ {
+		r := th.Lines.Vertical()
+		if y >= thumb && y < thumb+thumbSize {
+			r = th.thumb()
+		}
+		c.Set(rg.Min.Shift(0, y), common.Cell{Char: r, Foreground: st.Fg, Background: st.Bg})
+	}
+}
+
+// Menu draws a framed list with a title, see List. It returns done when an
+// item was chosen, with its index, or when the menu was closed with the menu
+// close key while focused, with -1.
+func (ctx *Context) Menu(c Canvas, id ID, rg geometry.Rect, title string, items []string, selected *int) (choice int, done bool) {
+	box.Panel(c, rg, ctx.Theme.Lines, ctx.Theme.Panel)
+	box.TitledFrame(c, rg, ctx.Theme.Lines, ctx.Theme.Panel, title, text.AlignCenter)
+	if ctx.List(c, id, rg.Shift(1, 1, -1, -1), items, selected) {
+		return *selected, true
+	}
+	if ctx.focus == id && !ctx.blocked() && ctx.in != nil && ctx.in.IsMenuClose() {
+		return -1, true
+	}
+	return *selected, false
+}
@@ -0,0 +1,226 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/memmaker/ECon/box"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/input"
+	"github.com/memmaker/ECon/text"
+)
+
+// Message is an entry of a MessageLog.
+type Message struct {
+	Text  string     // may contain text markup
+	Style text.Style // style outside of the markup, nil colors use the theme
+	Turn  int        // turn of the last repetition
+	Count int        // number of repetitions merged into the message
+}
+
+// String returns the text of the message with its repetition count, like
+// "You hit the rat x3".
+func (m Message) String() string {
+	if m.Count > 1 {
+		return fmt.Sprintf("%s x%d", m.Text, m.Count)
+	}
+	return m.Text
+}
+
+// DefaultMaxMessages is the number of messages kept by a MessageLog with a
+// zero MaxMessages.
+const DefaultMaxMessages = 500
+
+// MessageLog stores the messages shown to the player, drawn by
+// Context.MessageLog and Context.MessageHistory. The zero value is an empty
+// log.
+type MessageLog struct {
+	// MaxMessages is the number of messages kept, the oldest being dropped;
+	// 0 means DefaultMaxMessages.
+	MaxMessages int
+	// ShowTurns prefixes the messages with their turn.
+	ShowTurns bool
+
+	messages      []Message
+	scroll        int // lines scrolled up from the newest message
+	historyScroll int
+	layouts       []layout // cached by lines, emptied when the messages change
+}
+
+// maxLayouts is the number of layouts cached by a MessageLog, for the log and
+// the history panel, and a few more for resizes.
+const maxLayouts = 4
+
+// layout is the lines of the messages of a log laid out for a width.
+type layout struct {
+	width     int
+	fg        color.Color
+	showTurns bool
+	lines     [][]text.StyledRune
+}
+
+// Add adds a message at the given turn. A message with the same text and
+// style as the last one is merged into it.
+func (l *MessageLog) Add(turn int, msg string) {
+	l.AddStyled(turn, msg, text.Style{})
+}
+
+// Addf is like Add with a format string.
+func (l *MessageLog) Addf(turn int, format string, args ...interface{}) {
+	l.Add(turn, fmt.Sprintf(format, args...))
+}
+
+// AddStyled is like Add with a style for the parts of the text outside of the
+// markup.
+func (l *MessageLog) AddStyled(turn int, msg string, st text.Style) {
+	l.layouts = l.layouts[:0]
+	if n := len(l.messages); n > 0 && l.messages[n-1].Text == msg && l.messages[n-1].Style == st {
+		l.messages[n-1].Count++
+		l.messages[n-1].Turn = turn
+		return
+	}
+	l.messages = append(l.messages, Message{Text: msg, Style: st, Turn: turn, Count: 1})
+	max := l.MaxMessages
+	if max <= 0 {
+		max = DefaultMaxMessages
+	}
+	if len(l.messages) > max {
+		l.messages = append(l.messages[:0], l.messages[len(l.messages)-max:]...)
+	}
+}
+
+// Messages returns the messages, the oldest first.
+func (l *MessageLog) Messages() []Message {
+	return l.messages
+}
+
+// Clear removes all the messages.
+func (l *MessageLog) Clear() {
+	l.messages = l.messages[:0]
+	l.layouts = l.layouts[:0]
+	l.scroll = 0
+	l.historyScroll = 0
+}
+
+// lines returns the messages laid out for the given width, the oldest first.
+// The layouts are cached until the messages change.
+func (l *MessageLog) lines(width int, theme Theme) [][]text.StyledRune {
+	for _, lt := range l.layouts {
+		if lt.width == width && lt.fg == theme.Normal.Fg && lt.showTurns == l.ShowTurns {
+			return lt.lines
+		}
+	}
+	if len(l.layouts) == maxLayouts {
+		l.layouts = append(l.layouts[:0], l.layouts[1:]...)
+	}
+	lines := l.layout(width, theme)
+	l.layouts = append(l.layouts, layout{width: width, fg: theme.Normal.Fg, showTurns: l.ShowTurns, lines: lines})
+	return lines
+}
+
+// layout lays out the messages, the oldest first, for the given width.
+func (l *MessageLog) layout(width int, theme Theme) [][]text.StyledRune {
+	var lines [][]text.StyledRune
+	for _, m := range l.messages {
+		st := m.Style
+		if st.Fg == nil {
+			st.Fg = theme.Normal.Fg
+		}
+		s := m.String()
+		if l.ShowTurns {
+			s = fmt.Sprintf("[[%d] %s", m.Turn, s)
+		}
+		lines = append(lines, text.Layout(s, width, text.Options{Style: st, Wrap: true})...)
+	}
+	return lines
+}
+
+// scrollInput returns the number of lines to scroll up by, from the wheel when
+// the mouse is over rg and from the page keys.
+func (ctx *Context) scrollInput(rg geometry.Rect, page int) int {
+	if ctx.in == nil || ctx.blocked() {
+		return 0
+	}
+	delta := 0
+	if ctx.mouse.In(rg) {
+		delta += input.GetWheel(ctx.in) * 3
+	}
+	if ctx.IsKeyPressed("PageUp") {
+		delta += page
+	}
+	if ctx.IsKeyPressed("PageDown") {
+		delta -= page
+	}
+	return delta
+}
+
+// MessageLog draws the newest messages of a log, word-wrapped, at the bottom
+// of rg. The mouse wheel over the log and the PageUp and PageDown keys scroll
+// it back. The log is not focusable. Its background is left as is; nil
+// message colors use the theme's normal style.
+func (ctx *Context) MessageLog(c Canvas, rg geometry.Rect, log *MessageLog) {
+	size := rg.Size()
+	if size.X < 1 || size.Y < 1 {
+		return
+	}
+	lines := log.lines(size.X, ctx.Theme)
+	log.scroll = clamp(log.scroll+ctx.scrollInput(rg, size.Y-1), 0, max(0, len(lines)-size.Y))
+	end := len(lines) - log.scroll
+	start := max(0, end-size.Y)
+	visible := lines[start:end]
+	// the newest line at the bottom
+	text.PrintLines(c, rg.Lines(size.Y-len(visible), size.Y), visible, text.AlignLeft)
+	if log.scroll > 0 {
+		text.Print(c, rg.Line(size.Y-1).Columns(size.X-1, size.X), "↓", text.Options{Style: ctx.Theme.Focused})
+	}
+}
+
+// MessageHistory draws all the messages of a log in a modal panel covering
+// the canvas. It scrolls with the menu up and down keys, PageUp, PageDown,
+// Home, End and the mouse wheel, and reports closed when the menu close key
+// is pressed. The caller stops calling it once closed.
+func (ctx *Context) MessageHistory(c Canvas, id ID, title string, log *MessageLog) (closed bool) {
+	ctx.modal = id
+	ctx.inModal = true
+	defer func() { ctx.inModal = false }()
+
+	size := c.Size()
+	rg := geometry.NewRect(0, 0, size.X, size.Y)
+	box.Panel(c, rg, ctx.Theme.Lines, ctx.Theme.Panel)
+	box.TitledFrame(c, rg, ctx.Theme.Lines, ctx.Theme.Panel, title, text.AlignCenter)
+	inner := rg.Shift(1, 1, -1, -1)
+	height := inner.Size().Y
+	lines := log.lines(inner.Size().X, ctx.Theme)
+	maxScroll := max(0, len(lines)-height)
+	if ctx.lastModal != id {
+		log.historyScroll = 0
+	}
+	delta := ctx.scrollInput(inner, height-1)
+	if ctx.in != nil {
+		switch {
+		case ctx.in.IsMenuUp():
+			delta++
+		case ctx.in.IsMenuDown():
+			delta--
+		case ctx.IsKeyPressed("Home"):
+			delta = maxScroll
+		case ctx.IsKeyPressed("End"):
+			delta = -maxScroll
+		}
+	}
+	log.historyScroll = clamp(log.historyScroll+delta, 0, maxScroll)
+	end := len(lines) - log.historyScroll
+	start := max(0, end-height)
+	text.PrintLines(c, inner, lines[start:end], text.AlignLeft)
+	if len(lines) > height {
+		drawScrollBar(c, rg.Column(size.X-1).Shift(0, 1, 0, -1), maxScroll-log.historyScroll, len(lines), ctx.Theme.Panel, ctx.Theme)
+	}
+	return ctx.in != nil && ctx.in.IsMenuClose()
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/memmaker/ECon/text"
+)
+
+func lineStrings(lines [][]text.StyledRune) []string {
+	var s []string
+	for _, line := range lines {
+		var b strings.Builder
+		for _, r := range line {
+			b.WriteRune(r.Rune)
+		}
+		s = append(s, b.String())
+	}
+	return s
+}
+
+func TestMessageLogLines(t *testing.T) {
+	theme := DefaultTheme()
+	log := &MessageLog{}
+	log.Add(1, "You hit the rat")
+	log.Add(2, "You hit the rat")
+	log.Add(3, "The rat bites you")
+	got := strings.Join(lineStrings(log.lines(10, theme)), "|")
+	if want := "You hit|the rat x2|The rat|bites you"; got != want {
+		t.Errorf("lines(10) = %q, want %q", got, want)
+	}
+	log.ShowTurns = true
+	got = strings.Join(lineStrings(log.lines(20, theme)), "|")
+	if want := "[2] You hit the rat|x2|[3] The rat bites|you"; got != want {
+		t.Errorf("lines(20) with turns = %q, want %q", got, want)
+	}
+	log.Add(4, "The rat dies")
+	lines := lineStrings(log.lines(20, theme))
+	if got := lines[len(lines)-1]; got != "[4] The rat dies" {
+		t.Errorf("last line after Add = %q, want the new message", got)
+	}
+}
+
+func TestMessageLogLinesCached(t *testing.T) {
+	theme := DefaultTheme()
+	log := &MessageLog{}
+	for turn := 0; turn < 100; turn++ {
+		log.Addf(turn, "message %d with a few words to wrap", turn)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		log.lines(30, theme)
+		log.lines(60, theme)
+	})
+	if allocs != 0 {
+		t.Errorf("lines allocates %v times per call for the same widths, want 0", allocs)
+	}
+}
@@ -0,0 +1,369 @@
+package ui
+
+import (
+	"unicode"
+
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/input"
+)
+
+// TextField is the state of a text entry widget, drawn by
+// Context.TextField: its text, the cursor, the selection and the history of
+// the submitted texts. The zero value is an empty single-line field.
+type TextField struct {
+	// MaxLength is the maximum number of runes, 0 for no limit.
+	MaxLength int
+	// Multiline fields insert a newline on Enter, and use the up and down
+	// keys to move between lines. Single-line fields submit their text on
+	// Enter and browse the history with the up and down keys.
+	Multiline bool
+	// Accept, if set, filters the typed runes, for example to only accept
+	// digits. Control characters are never accepted.
+	Accept func(r rune) bool
+	// Validate, if set, is called with the text resulting from an edit, and
+	// the edit is rejected if it returns false.
+	Validate func(text string) bool
+	// MaxHistory is the number of submitted texts kept, 0 for no history.
+	MaxHistory int
+
+	text    []rune
+	cursor  int // rune index
+	anchor  int // other end of the selection, equal to cursor if none
+	history []string
+	browse  int // index in history while browsing it, len(history) otherwise
+	draft   string
+	scroll  geometry.Point
+}
+
+// Text returns the content of the field.
+func (f *TextField) Text() string {
+	return string(f.text)
+}
+
+// SetText replaces the content of the field and moves the cursor to its end.
+// It is not validated.
+func (f *TextField) SetText(s string) {
+	f.text = []rune(s)
+	if f.MaxLength > 0 && len(f.text) > f.MaxLength {
+		f.text = f.text[:f.MaxLength]
+	}
+	f.cursor = len(f.text)
+	f.anchor = f.cursor
+}
+
+// Cursor returns the position of the cursor, as a rune index.
+func (f *TextField) Cursor() int {
+	return f.cursor
+}
+
+// Selection returns the range of selected runes, empty if none.
+func (f *TextField) Selection() (start, end int) {
+	if f.anchor < f.cursor {
+		return f.anchor, f.cursor
+	}
+	return f.cursor, f.anchor
+}
+
+// Select selects the runes from start to end, the cursor being at end.
+func (f *TextField) Select(start, end int) {
+	f.anchor = clamp(start, 0, len(f.text))
+	f.cursor = clamp(end, 0, len(f.text))
+}
+
+// SelectAll selects the whole text.
+func (f *TextField) SelectAll() {
+	f.Select(0, len(f.text))
+}
+
+// Insert replaces the selection with s at the cursor, as if typed, and
+// reports whether the edit was accepted. Runes beyond MaxLength are dropped.
+func (f *TextField) Insert(s string) bool {
+	start, end := f.Selection()
+	runes := make([]rune, 0, len(s))
+	for _, r := range s {
+		if f.accepts(r) {
+			runes = append(runes, r)
+		}
+	}
+	if f.MaxLength > 0 {
+		room := f.MaxLength - (len(f.text) - (end - start))
+		if room < 0 {
+			room = 0
+		}
+		if len(runes) > room {
+			runes = runes[:room]
+		}
+	}
+	if len(runes) == 0 && start == end {
+		return false
+	}
+	return f.replace(start, end, runes)
+}
+
+func (f *TextField) accepts(r rune) bool {
+	if r == '\n' {
+		return f.Multiline
+	}
+	if unicode.IsControl(r) {
+		return false
+	}
+	return f.Accept == nil || f.Accept(r)
+}
+
+// replace replaces the runes from start to end, if the result is valid, and
+// puts the cursor after the new runes.
+func (f *TextField) replace(start, end int, runes []rune) bool {
+	text := make([]rune, 0, len(f.text)-(end-start)+len(runes))
+	text = append(text, f.text[:start]...)
+	text = append(text, runes...)
+	text = append(text, f.text[end:]...)
+	if f.Validate != nil && !f.Validate(string(text)) {
+		return false
+	}
+	f.text = text
+	f.cursor = start + len(runes)
+	f.anchor = f.cursor
+	return true
+}
+
+// deleteBackward deletes the selection, or the rune before the cursor.
+func (f *TextField) deleteBackward() bool {
+	start, end := f.Selection()
+	if start == end {
+		if start == 0 {
+			return false
+		}
+		start--
+	}
+	return f.replace(start, end, nil)
+}
+
+// deleteForward deletes the selection, or the rune after the cursor.
+func (f *TextField) deleteForward() bool {
+	start, end := f.Selection()
+	if start == end {
+		if end == len(f.text) {
+			return false
+		}
+		end++
+	}
+	return f.replace(start, end, nil)
+}
+
+// moveTo moves the cursor, extending the selection if extend is set.
+func (f *TextField) moveTo(pos int, extend bool) {
+	f.cursor = clamp(pos, 0, len(f.text))
+	if !extend {
+		f.anchor = f.cursor
+	}
+}
+
+// Submit adds the text to the history and clears the field. It returns the
+// submitted text.
+func (f *TextField) Submit() string {
+	s := f.Text()
+	if f.MaxHistory > 0 && s != "" {
+		if len(f.history) == 0 || f.history[len(f.history)-1] != s {
+			f.history = append(f.history, s)
+		}
+		if len(f.history) > f.MaxHistory {
+			f.history = f.history[len(f.history)-f.MaxHistory:]
+		}
+	}
+	f.browse = len(f.history)
+	f.draft = ""
+	f.SetText("")
+	return s
+}
+
+// History returns the submitted texts, the oldest first.
+func (f *TextField) History() []string {
+	return f.history
+}
+
+// browseHistory shows the previous (delta -1) or next (delta 1) text of the
+// history. The text being edited is kept as a draft after the last one.
+func (f *TextField) browseHistory(delta int) {
+	if f.browse > len(f.history) {
+		f.browse = len(f.history)
+	}
+	next := f.browse + delta
+	if next < 0 || next > len(f.history) {
+		return
+	}
+	if f.browse == len(f.history) {
+		f.draft = f.Text()
+	}
+	f.browse = next
+	if next == len(f.history) {
+		f.SetText(f.draft)
+	} else {
+		f.SetText(f.history[next])
+	}
+}
+
+// position returns the line and column of a rune index.
+func (f *TextField) position(index int) geometry.Point {
+	var p geometry.Point
+	for _, r := range f.text[:index] {
+		if r == '\n' {
+			p.Y++
+			p.X = 0
+		} else {
+			p.X++
+		}
+	}
+	return p
+}
+
+// index returns the rune index of a line and column, clamped to the text.
+func (f *TextField) index(p geometry.Point) int {
+	if p.Y < 0 {
+		return 0
+	}
+	line, col := 0, 0
+	for i, r := range f.text {
+		if line == p.Y && (col >= p.X || r == '\n') {
+			return i
+		}
+		if r == '\n' {
+			line++
+			col = 0
+		} else {
+			col++
+		}
+	}
+	return len(f.text)
+}
+
+// handleKeys applies the keys and typed runes of the frame, and reports
+// whether the text was submitted.
+func (f *TextField) handleKeys(ctx *Context) bool {
+	extend := input.IsShiftDown(ctx.in)
+	for _, key := range ctx.keys {
+		switch key {
+		case "ArrowLeft":
+			f.moveLeft(extend)
+		case "ArrowRight":
+			f.moveRight(extend)
+		case "Home":
+			f.moveTo(f.index(geometry.Point{Y: f.position(f.cursor).Y}), extend)
+		case "End":
+			f.moveTo(f.index(geometry.Point{X: len(f.text), Y: f.position(f.cursor).Y}), extend)
+		case "ArrowUp", "ArrowDown":
+			delta := 1
+			if key == "ArrowUp" {
+				delta = -1
+			}
+			if f.Multiline {
+				p := f.position(f.cursor)
+				f.moveTo(f.index(geometry.Point{X: p.X, Y: p.Y + delta}), extend)
+			} else {
+				f.browseHistory(delta)
+			}
+		case "Backspace":
+			f.deleteBackward()
+		case "Delete":
+			f.deleteForward()
+		case "Enter", "NumpadEnter":
+			if !f.Multiline {
+				return true
+			}
+			f.Insert("\n")
+		}
+	}
+	if chars := ctx.in.GetInputChars(); len(chars) > 0 {
+		f.Insert(string(chars))
+	}
+	return false
+}
+
+func (f *TextField) moveLeft(extend bool) {
+	start, end := f.Selection()
+	if start != end && !extend {
+		f.moveTo(start, false)
+		return
+	}
+	f.moveTo(f.cursor-1, extend)
+}
+
+func (f *TextField) moveRight(extend bool) {
+	start, end := f.Selection()
+	if start != end && !extend {
+		f.moveTo(end, false)
+		return
+	}
+	f.moveTo(f.cursor+1, extend)
+}
+
+// TextField draws a text entry field and edits it with the keys and the
+// typed runes while it is focused. A click moves the cursor. Shift extends
+// the selection with the cursor keys, when the input reports it (see
+// input.ModifierInput). TextField reports whether a single-line field was
+// submitted with Enter; the caller usually calls f.Submit then.
+func (ctx *Context) TextField(c Canvas, id ID, rg geometry.Rect, f *TextField) bool {
+	size := rg.Size()
+	if size.X < 1 || size.Y < 1 {
+		return false
+	}
+	height := size.Y
+	if !f.Multiline {
+		height = 1
+		rg = rg.Line(0)
+	}
+	it := ctx.interact(id, rg)
+	if it.clicked {
+		p := ctx.mouse.Sub(rg.Min).Add(f.scroll)
+		f.moveTo(f.index(p), false)
+	}
+	submitted := false
+	if it.focused {
+		ctx.usedVertical = true
+		submitted = f.handleKeys(ctx)
+	}
+
+	// scroll to keep the cursor visible
+	cursor := f.position(f.cursor)
+	f.scroll.X = scrollAxis(f.scroll.X, cursor.X, size.X)
+	f.scroll.Y = scrollAxis(f.scroll.Y, cursor.Y, height)
+
+	st := ctx.style(it)
+	fill(c, rg, st)
+	start, end := f.Selection()
+	var p geometry.Point
+	for i := 0; i <= len(f.text); i++ {
+		q := p.Sub(f.scroll)
+		if q.X >= 0 && q.X < size.X && q.Y >= 0 && q.Y < height {
+			cell := common.Cell{Char: ' ', Foreground: st.Fg, Background: st.Bg}
+			if i < len(f.text) && f.text[i] != '\n' {
+				cell.Char = f.text[i]
+			}
+			if i >= start && i < end {
+				cell.Foreground, cell.Background = ctx.Theme.Selected.Fg, ctx.Theme.Selected.Bg
+			}
+			if i == f.cursor && it.focused {
+				cell.Foreground, cell.Background = cell.Background, cell.Foreground
+			}
+			c.Set(rg.Min.Add(q), cell)
+		}
+		if i < len(f.text) && f.text[i] == '\n' {
+			p.X = 0
+			p.Y++
+		} else {
+			p.X++
+		}
+	}
+	return submitted
+}
+
+// scrollAxis returns the scroll offset keeping pos within size cells.
+func scrollAxis(offset, pos, size int) int {
+	if pos < offset {
+		return pos
+	}
+	if pos >= offset+size {
+		return pos - size + 1
+	}
+	return offset
+}
@@ -0,0 +1,222 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/memmaker/ECon/console/headless"
+	"github.com/memmaker/ECon/geometry"
+)
+
+func TestTextFieldInsert(t *testing.T) {
+	tests := []struct {
+		name       string
+		field      TextField
+		text       string
+		start, end int // selection before the insertion
+		insert     string
+		want       string
+		cursor     int
+		ok         bool
+	}{
+		{"empty", TextField{}, "", 0, 0, "abc", "abc", 3, true},
+		{"middle", TextField{}, "ad", 1, 1, "bc", "abcd", 3, true},
+		{"selection", TextField{}, "hello world", 6, 11, "there", "hello there", 11, true},
+		{"reversed selection", TextField{}, "hello world", 11, 6, "there", "hello there", 11, true},
+		{"selection deleted", TextField{}, "abc", 0, 1, "", "bc", 0, true},
+		{"nothing", TextField{}, "abc", 1, 1, "", "abc", 1, false},
+		{"max length trims", TextField{MaxLength: 5}, "abc", 3, 3, "defgh", "abcde", 5, true},
+		{"max length full", TextField{MaxLength: 3}, "abc", 3, 3, "d", "abc", 3, false},
+		{"max length with selection", TextField{MaxLength: 5}, "abcde", 1, 3, "xyz", "axyde", 3, true},
+		{"accept", TextField{Accept: unicode.IsDigit}, "", 0, 0, "a1b2", "12", 2, true},
+		{"control runes", TextField{}, "", 0, 0, "a\tb\nc", "abc", 3, true},
+		{"multiline newline", TextField{Multiline: true}, "", 0, 0, "a\nb", "a\nb", 3, true},
+		{"validate rejects", TextField{Validate: func(s string) bool { return len(s) <= 3 }}, "ab", 2, 2, "cd", "ab", 2, false},
+		{"validate accepts", TextField{Validate: func(s string) bool { return len(s) <= 3 }}, "ab", 2, 2, "c", "abc", 3, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.field
+			f.SetText(tt.text)
+			f.Select(tt.start, tt.end)
+			ok := f.Insert(tt.insert)
+			start, end := f.Selection()
+			if ok != tt.ok || f.Text() != tt.want || f.Cursor() != tt.cursor || start != end {
+				t.Errorf("Insert(%q) = %v, text %q, cursor %d, selection %d-%d, want %v, %q, %d and no selection",
+					tt.insert, ok, f.Text(), f.Cursor(), start, end, tt.ok, tt.want, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestTextFieldDelete(t *testing.T) {
+	notEmpty := func(s string) bool { return s != "" }
+	tests := []struct {
+		name       string
+		validate   func(string) bool
+		text       string
+		start, end int
+		forward    bool
+		want       string
+		cursor     int
+		ok         bool
+	}{
+		{"backward", nil, "abc", 3, 3, false, "ab", 2, true},
+		{"backward at start", nil, "abc", 0, 0, false, "abc", 0, false},
+		{"forward", nil, "abc", 1, 1, true, "ac", 1, true},
+		{"forward at end", nil, "abc", 3, 3, true, "abc", 3, false},
+		{"backward selection", nil, "abc", 0, 2, false, "c", 0, true},
+		{"forward selection", nil, "abc", 3, 1, true, "a", 1, true},
+		{"validate rejects", notEmpty, "a", 1, 1, false, "a", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &TextField{}
+			f.SetText(tt.text)
+			f.Validate = tt.validate
+			f.Select(tt.start, tt.end)
+			var ok bool
+			if tt.forward {
+				ok = f.deleteForward()
+			} else {
+				ok = f.deleteBackward()
+			}
+			if ok != tt.ok || f.Text() != tt.want || f.Cursor() != tt.cursor {
+				t.Errorf("delete = %v, text %q, cursor %d, want %v, %q, %d", ok, f.Text(), f.Cursor(), tt.ok, tt.want, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestTextFieldHistory(t *testing.T) {
+	f := &TextField{MaxHistory: 2}
+	for _, s := range []string{"one", "two", "", "two", "three"} {
+		f.SetText(s)
+		if got := f.Submit(); got != s || f.Text() != "" {
+			t.Fatalf("Submit() = %q, text %q, want %q and an empty field", got, f.Text(), s)
+		}
+	}
+	if got := strings.Join(f.History(), "|"); got != "two|three" {
+		t.Fatalf("History() = %q, want the last 2 distinct texts", got)
+	}
+
+	f.SetText("draft")
+	steps := []struct {
+		delta int
+		want  string
+	}{
+		{-1, "three"},
+		{-1, "two"},
+		{-1, "two"}, // oldest
+		{1, "three"},
+		{1, "draft"},
+		{1, "draft"}, // after the newest
+		{-1, "three"},
+	}
+	for i, st := range steps {
+		f.browseHistory(st.delta)
+		if f.Text() != st.want || f.Cursor() != len([]rune(st.want)) {
+			t.Fatalf("step %d: text %q, cursor %d, want %q with the cursor at its end", i, f.Text(), f.Cursor(), st.want)
+		}
+	}
+	f.Submit()
+	f.browseHistory(-1)
+	if f.Text() != "three" {
+		t.Errorf("text after a submit and a step back = %q, want the last submitted", f.Text())
+	}
+}
+
+func TestTextFieldPositions(t *testing.T) {
+	f := &TextField{Multiline: true}
+	f.SetText("ab\ncde\n\nf")
+	for _, tt := range []struct {
+		index int
+		p     geometry.Point
+	}{
+		{0, geometry.Point{X: 0, Y: 0}},
+		{2, geometry.Point{X: 2, Y: 0}},
+		{3, geometry.Point{X: 0, Y: 1}},
+		{6, geometry.Point{X: 3, Y: 1}},
+		{7, geometry.Point{X: 0, Y: 2}},
+		{8, geometry.Point{X: 0, Y: 3}},
+		{9, geometry.Point{X: 1, Y: 3}},
+	} {
+		if got := f.position(tt.index); got != tt.p {
+			t.Errorf("position(%d) = %v, want %v", tt.index, got, tt.p)
+		}
+		if got := f.index(tt.p); got != tt.index {
+			t.Errorf("index(%v) = %d, want %d", tt.p, got, tt.index)
+		}
+	}
+	for _, tt := range []struct {
+		p     geometry.Point
+		index int
+	}{
+		{geometry.Point{X: 5, Y: 0}, 2}, // end of the line
+		{geometry.Point{X: 9, Y: 2}, 7}, // empty line
+		{geometry.Point{X: 0, Y: 9}, 9}, // past the last line
+		{geometry.Point{X: 3, Y: -1}, 0},
+	} {
+		if got := f.index(tt.p); got != tt.index {
+			t.Errorf("index(%v) = %d, want %d, clamped", tt.p, got, tt.index)
+		}
+	}
+}
+
+func TestTextFieldKeys(t *testing.T) {
+	tests := []struct {
+		name           string
+		multiline      bool
+		text           string
+		anchor, cursor int
+		shift          bool
+		keys           []string // one frame per key
+		wantAnchor     int
+		wantCursor     int
+		want           string
+		submitted      bool
+	}{
+		{"up", true, "ab\ncde", 5, 5, false, []string{"ArrowUp"}, 2, 2, "ab\ncde", false},
+		{"up on the first line", true, "ab\ncde", 1, 1, false, []string{"ArrowUp"}, 0, 0, "ab\ncde", false},
+		{"down", true, "ab\ncde", 1, 1, false, []string{"ArrowDown"}, 4, 4, "ab\ncde", false},
+		{"down on the last line", true, "ab\ncde", 4, 4, false, []string{"ArrowDown"}, 6, 6, "ab\ncde", false},
+		{"home", true, "ab\ncde", 5, 5, false, []string{"Home"}, 3, 3, "ab\ncde", false},
+		{"end", true, "ab\ncde", 0, 0, false, []string{"End"}, 2, 2, "ab\ncde", false},
+		{"shift home", true, "ab\ncde", 5, 5, true, []string{"Home"}, 5, 3, "ab\ncde", false},
+		{"shift left", true, "ab\ncde", 5, 5, true, []string{"ArrowLeft", "ArrowLeft"}, 5, 3, "ab\ncde", false},
+		{"left collapses", true, "ab\ncde", 3, 5, false, []string{"ArrowLeft"}, 3, 3, "ab\ncde", false},
+		{"right collapses", true, "ab\ncde", 5, 3, false, []string{"ArrowRight"}, 5, 5, "ab\ncde", false},
+		{"enter", true, "ab\ncde", 4, 4, false, []string{"Enter"}, 5, 5, "ab\nc\nde", false},
+		{"backspace", true, "ab\ncde", 3, 3, false, []string{"Backspace"}, 2, 2, "abcde", false},
+		{"history", false, "new", 3, 3, false, []string{"ArrowUp"}, 3, 3, "old", false},
+		{"draft", false, "new", 3, 3, false, []string{"ArrowUp", "ArrowDown"}, 3, 3, "new", false},
+		{"submit", false, "new", 3, 3, false, []string{"Enter"}, 3, 3, "new", true},
+	}
+	c := newCanvas(10, 3)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			ctx.SetFocus("field")
+			f := &TextField{Multiline: tt.multiline, MaxHistory: 5}
+			f.SetText("old")
+			f.Submit()
+			f.SetText(tt.text)
+			f.Select(tt.anchor, tt.cursor)
+			in := headless.NewInput()
+			in.HoldShift(tt.shift)
+			submitted := false
+			for _, key := range tt.keys {
+				in.PressKeys(key)
+				ctx.Begin(in)
+				submitted = ctx.TextField(c, "field", c.Range(), f)
+				ctx.End(c)
+				in.EndFrame()
+			}
+			if f.anchor != tt.wantAnchor || f.cursor != tt.wantCursor || f.Text() != tt.want || submitted != tt.submitted {
+				t.Errorf("anchor %d, cursor %d, text %q, submitted %v, want %d, %d, %q, %v",
+					f.anchor, f.cursor, f.Text(), submitted, tt.wantAnchor, tt.wantCursor, tt.want, tt.submitted)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package ui
+
+import (
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/geometry"
+	"github.com/memmaker/ECon/text"
+)
+
+// Label prints a text, which may contain markup, with the normal style. It is
+// not focusable.
+func (ctx *Context) Label(c Canvas, rg geometry.Rect, s string) {
+	fill(c, rg, ctx.Theme.Normal)
+	text.Print(c, rg, s, text.Options{Style: ctx.Theme.Normal, Wrap: true})
+}
+
+// Button draws a button with a centered label and reports whether it was
+// clicked, or activated with the keyboard.
+func (ctx *Context) Button(c Canvas, id ID, rg geometry.Rect, label string) bool {
+	it := ctx.interact(id, rg)
+	st := ctx.style(it)
+	fill(c, rg, st)
+	line := rg.Line(rg.Size().Y / 2)
+	text.Print(c, line, label, text.Options{Style: st, Align: text.AlignCenter})
+	return it.activated
+}
+
+// Checkbox draws a check box followed by a label, toggles the value when
+// activated, and reports whether it changed.
+func (ctx *Context) Checkbox(c Canvas, id ID, rg geometry.Rect, label string, value *bool) bool {
+	it := ctx.interact(id, rg)
+	if it.activated {
+		*value = !*value
+	}
+	st := ctx.style(it)
+	fill(c, rg, st)
+	box := "[[ ] "
+	if *value {
+		box = "[[x] "
+	}
+	text.Print(c, rg.Line(0), box+label, text.Options{Style: st})
+	return it.activated
+}
+
+// Slider draws a horizontal slider for an integer value between min and max
+// included, on the first line of rg. The value is changed by clicking on the
+// slider, or with the left and right arrow keys when it is focused. Slider
+// reports whether the value changed.
+func (ctx *Context) Slider(c Canvas, id ID, rg geometry.Rect, value *int, min, max int) bool {
+	rg = rg.Line(0)
+	width := rg.Size().X
+	it := ctx.interact(id, rg)
+	old := *value
+	switch {
+	case it.clicked && width > 1:
+		*value = min + (ctx.mouse.X-rg.Min.X)*(max-min)/(width-1)
+	case it.focused && ctx.IsKeyPressed("ArrowLeft"):
+		*value--
+	case it.focused && ctx.IsKeyPressed("ArrowRight"):
+		*value++
+	}
+	*value = clamp(*value, min, max)
+
+	st := ctx.style(it)
+	knob := 0
+	if max > min {
+		knob = (*value - min) * (width - 1) / (max - min)
+	}
+	for x := 0; x < width; x++ {
+		r := ctx.Theme.Lines.Horizontal()
+		if x == knob {
+			r = ctx.Theme.thumb()
+		}
+		c.Set(rg.Min.Shift(x, 0), common.Cell{Char: r, Foreground: st.Fg, Background: st.Bg})
+	}
+	return *value != old
+}
+
+func clamp(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
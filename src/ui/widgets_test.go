@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/memmaker/ECon/box"
+	"github.com/memmaker/ECon/common"
+	"github.com/memmaker/ECon/console/headless"
+	"github.com/memmaker/ECon/geometry"
+)
+
+// newCanvas returns a grid of blanks.
+func newCanvas(w, h int) geometry.Grid {
+	gd := geometry.NewGrid(w, h)
+	gd.Fill(common.Cell{Char: ' ', Foreground: common.White, Background: common.Black})
+	return gd
+}
+
+// column returns the runes of a column of a grid.
+func column(gd geometry.Grid, x int) string {
+	var b strings.Builder
+	for y := 0; y < gd.Size().Y; y++ {
+		b.WriteRune(gd.At(geometry.Point{X: x, Y: y}).Char)
+	}
+	return b.String()
+}
+
+func TestSlider(t *testing.T) {
+	tests := []struct {
+		lines box.LineStyle
+		click bool
+		want  string
+		value int
+	}{
+		{box.Single, false, "──█──\n", 2},
+		{box.Double, false, "══█══\n", 2},
+		{box.ASCII, false, "--#--\n", 2},
+		{box.Single, true, "────█\n", 4},
+	}
+	for _, tt := range tests {
+		ctx := NewContext()
+		ctx.Theme.Lines = tt.lines
+		in := headless.NewInput()
+		if tt.click {
+			in.ClickLeft(geometry.Point{X: 4})
+		}
+		ctx.Begin(in)
+		c := newCanvas(5, 1)
+		value := 2
+		changed := ctx.Slider(c, "slider", c.Range(), &value, 0, 4)
+		ctx.End(c)
+		if got := c.String(); got != tt.want || value != tt.value || changed != tt.click {
+			t.Errorf("Slider with %v lines, click %v = %q, value %d, changed %v, want %q, %d, %v",
+				tt.lines, tt.click, got, value, changed, tt.want, tt.value, tt.click)
+		}
+	}
+}
+
+func TestListScrollBar(t *testing.T) {
+	items := []string{"a", "b", "c", "d", "e"}
+	tests := []struct {
+		lines    box.LineStyle
+		selected int
+		want     string
+	}{
+		{box.Single, 0, "█││"},
+		{box.Heavy, 4, "┃┃█"},
+		{box.ASCII, 0, "#||"},
+		{box.ASCII, 3, "|#|"},
+	}
+	for _, tt := range tests {
+		ctx := NewContext()
+		ctx.Theme.Lines = tt.lines
+		ctx.Begin(headless.NewInput())
+		c := newCanvas(4, 3)
+		selected := tt.selected
+		ctx.List(c, "list", c.Range(), items, &selected)
+		ctx.End(c)
+		if got := column(c, 3); got != tt.want {
+			t.Errorf("scroll bar with %v lines and item %d selected = %q, want %q", tt.lines, tt.selected, got, tt.want)
+		}
+	}
+}
+
+func TestListSelection(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     []string
+		selected  int
+		keys      []string
+		want      int
+		confirmed bool
+	}{
+		{"clamped", []string{"a", "b", "c"}, 10, nil, 2, false},
+		{"down", []string{"a", "b", "c"}, 0, []string{"ArrowDown"}, 1, false},
+		{"confirmed", []string{"a", "b", "c"}, 1, []string{"Enter"}, 1, true},
+		{"empty", nil, 3, nil, 3, false},
+		{"empty confirmed", nil, 3, []string{"Enter"}, 3, false},
+		{"empty down", nil, 0, []string{"ArrowDown"}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := NewContext()
+			ctx.SetFocus("list")
+			in := headless.NewInput()
+			in.PressKeys(tt.keys...)
+			ctx.Begin(in)
+			c := newCanvas(4, 3)
+			selected := tt.selected
+			confirmed := ctx.List(c, "list", c.Range(), tt.items, &selected)
+			ctx.End(c)
+			if selected != tt.want || confirmed != tt.confirmed {
+				t.Errorf("List() = %v, selected %d, want %v, %d", confirmed, selected, tt.confirmed, tt.want)
+			}
+		})
+	}
+}